@@ -0,0 +1,81 @@
+package routem
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+// HTTPHandler adapts a HandlerFunc into an http.Handler so it can be
+// served by other routers. The context passed to the handler is built
+// with NewRequestContext from the context of the request, with the
+// DefaultTimeout. Params already carried by the request, as when it
+// is served from within a Route, are passed on.
+//
+// If the handler returns an HTTPError it is passed to the
+// errorHandler. Without an errorHandler the code and message of the
+// error are sent to the client. If the errorHandler returns an error
+// an Internal Server Error is sent instead.
+func HTTPHandler(handler HandlerFunc, errorHandler ErrorHandlerFunc) http.Handler {
+	return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		ctx, cancel := NewRequestContext(request.Context(), DefaultTimeout, request, response, ParamsFromRequest(request))
+
+		defer cancel()
+
+		err := handler(ctx)
+
+		if err == nil {
+			return
+		}
+
+		if errorHandler == nil {
+			http.Error(response, err.Error(), err.Code())
+		} else if errErr := errorHandler(err, ctx); errErr != nil {
+			http.Error(response, fmt.Sprintf("Internal Server Error: %s", errErr), http.StatusInternalServerError)
+		}
+	})
+}
+
+// HTTPMiddleware adapts net/http middleware into a MiddlewareFunc.
+//
+// The middleware is handed a request carrying the routem context, so
+// values it adds to the context of the request, and any request or
+// http.ResponseWriter it replaces, are seen by the rest of the stack
+// along with the Params of the request. A context the middleware
+// replaces entirely falls back to the routem context for the values
+// it lacks. If the middleware does not call the handler it was given
+// the rest of the stack is skipped and no error is returned.
+//
+// The middleware should call the handler before it returns. An error
+// from a call which finishes afterwards, such as one made on another
+// goroutine by http.TimeoutHandler once it has given up, is dropped.
+func HTTPMiddleware(middleware func(http.Handler) http.Handler) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context) HTTPError {
+			var mutex sync.Mutex
+			var err HTTPError
+
+			handler := middleware(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+				c := request.Context()
+				if _, ok := c.Value(requestKey).(requestData); !ok {
+					c = rootedContext{Context: c, root: ctx}
+				}
+
+				nextErr := next(withRequest(c, request, response))
+
+				mutex.Lock()
+				err = nextErr
+				mutex.Unlock()
+			}))
+
+			handler.ServeHTTP(ResponseWriterFromContext(ctx), RequestFromContext(ctx).WithContext(ctx))
+
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			return err
+		}
+	}
+}
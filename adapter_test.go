@@ -0,0 +1,173 @@
+package routem
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"golang.org/x/net/context"
+)
+
+type adapterKey int
+
+func serveAdapter(t *testing.T, handler http.Handler) *httptest.ResponseRecorder {
+	request, err := http.NewRequest("GET", "http://localhost/test", nil)
+	require.Nil(t, err)
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+
+	return response
+}
+
+func TestHTTPHandler(t *testing.T) {
+	handler := HTTPHandler(func(ctx context.Context) HTTPError {
+		_, hasDeadline := ctx.Deadline()
+		assert.True(t, hasDeadline)
+		fmt.Fprint(ResponseWriterFromContext(ctx), RequestFromContext(ctx).URL.Path)
+		return nil
+	}, nil)
+
+	response := serveAdapter(t, handler)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "/test", response.Body.String())
+}
+
+func TestHTTPHandlerError(t *testing.T) {
+	failing := func(ctx context.Context) HTTPError {
+		return NewHTTPError(http.StatusTeapot, fmt.Errorf("Teapot"))
+	}
+
+	response := serveAdapter(t, HTTPHandler(failing, nil))
+	assert.Equal(t, http.StatusTeapot, response.Code)
+	assert.Equal(t, "Teapot\n", response.Body.String())
+
+	response = serveAdapter(t, HTTPHandler(failing, func(err HTTPError, ctx context.Context) error {
+		http.Error(ResponseWriterFromContext(ctx), "Handled", http.StatusBadRequest)
+		return nil
+	}))
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	assert.Equal(t, "Handled\n", response.Body.String())
+
+	response = serveAdapter(t, HTTPHandler(failing, func(err HTTPError, ctx context.Context) error {
+		return fmt.Errorf("Error handling an error: %v", err)
+	}))
+	assert.Equal(t, http.StatusInternalServerError, response.Code)
+}
+
+func TestHTTPHandlerKeepsParams(t *testing.T) {
+	inner := HTTPHandler(func(ctx context.Context) HTTPError {
+		fmt.Fprint(ResponseWriterFromContext(ctx), ParamsFromContext(ctx)["id"])
+		return nil
+	}, nil)
+
+	request, err := http.NewRequest("GET", "http://localhost/users/42", nil)
+	require.Nil(t, err)
+	response := httptest.NewRecorder()
+
+	ctx, cancel := NewRequestContext(context.Background(), DefaultTimeout, request, response, Params{"id": "42"})
+	defer cancel()
+
+	inner.ServeHTTP(response, request.WithContext(ctx))
+	assert.Equal(t, "42", response.Body.String())
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	middleware := HTTPMiddleware(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			response.Header().Set("X-Middleware", "true")
+			ctx := context.WithValue(request.Context(), adapterKey(0), "value")
+			next.ServeHTTP(response, request.WithContext(ctx))
+		})
+	})
+
+	var request *http.Request
+	handler := middleware(func(ctx context.Context) HTTPError {
+		request = RequestFromContext(ctx)
+		assert.Equal(t, "value", ctx.Value(adapterKey(0)))
+		assert.Equal(t, "value", request.Context().Value(adapterKey(0)))
+		assert.Equal(t, "42", ParamsFromContext(ctx)["id"])
+		return NewHTTPError(http.StatusTeapot, fmt.Errorf("Teapot"))
+	})
+
+	response, original, _ := setupContextTest(t)
+	ctx, cancel := NewRequestContext(context.Background(), DefaultTimeout, original, response, Params{"id": "42"})
+	defer cancel()
+
+	err := handler(ctx)
+
+	require.NotNil(t, err)
+	assert.Equal(t, http.StatusTeapot, err.Code())
+	assert.Equal(t, "true", response.Header().Get("X-Middleware"))
+	require.NotNil(t, request)
+	assert.Equal(t, original.URL, request.URL)
+}
+
+func TestHTTPMiddlewareShortCircuit(t *testing.T) {
+	middleware := HTTPMiddleware(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			http.Error(response, "Forbidden", http.StatusForbidden)
+		})
+	})
+
+	called := false
+	handler := HTTPHandler(middleware(func(ctx context.Context) HTTPError {
+		called = true
+		return nil
+	}), nil)
+
+	response := serveAdapter(t, handler)
+	assert.False(t, called)
+	assert.Equal(t, http.StatusForbidden, response.Code)
+}
+
+func TestHTTPMiddlewareReplacedContext(t *testing.T) {
+	middleware := HTTPMiddleware(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			ctx := context.WithValue(context.Background(), adapterKey(0), "value")
+			next.ServeHTTP(response, request.WithContext(ctx))
+		})
+	})
+
+	called := false
+	handler := middleware(func(ctx context.Context) HTTPError {
+		called = true
+		assert.Equal(t, "value", ctx.Value(adapterKey(0)))
+		assert.Equal(t, "42", ParamsFromContext(ctx)["id"])
+		return nil
+	})
+
+	response, request, _ := setupContextTest(t)
+	ctx, cancel := NewRequestContext(context.Background(), DefaultTimeout, request, response, Params{"id": "42"})
+	defer cancel()
+
+	assert.Nil(t, handler(ctx))
+	assert.True(t, called)
+}
+
+func TestHTTPMiddlewareTimeoutHandler(t *testing.T) {
+	finished := make(chan struct{})
+
+	middleware := HTTPMiddleware(func(next http.Handler) http.Handler {
+		return http.TimeoutHandler(next, 10*time.Millisecond, "Timed Out")
+	})
+
+	// The handler returns on another goroutine once the middleware
+	// has given up on it
+	handler := HTTPHandler(middleware(func(ctx context.Context) HTTPError {
+		defer close(finished)
+		<-ctx.Done()
+		return NewHTTPError(http.StatusTeapot, fmt.Errorf("Teapot"))
+	}), nil)
+
+	response := serveAdapter(t, handler)
+	assert.Equal(t, http.StatusServiceUnavailable, response.Code)
+
+	<-finished
+}
@@ -25,6 +25,12 @@ const (
 	DefaultTimeout time.Duration = 2 * time.Second // Two seconds should be enough, right?
 )
 
+// NoTimeout, or any other timeout which is not positive, lets a Route
+// run until it returns or its context is cancelled. The HandlerFactory
+// implementations in this repository run such Routes on the serving
+// goroutine rather than one of their own.
+const NoTimeout time.Duration = 0
+
 type (
 	// Method is the type for HTTP Methods
 	Method string
@@ -45,11 +51,13 @@ type (
 	}
 
 	// A Routable is a Group or a Route which can be configured
-	// and has a path.
+	// and has a path. It may also be restricted to a host, in
+	// which case Host() returns the host pattern.
 	Routable interface {
 		RouteConfigurator
 
 		Path() string
+		Host() string
 	}
 
 	// A RouteCreator knows how to construct routes and Groups,
@@ -83,14 +91,51 @@ type (
 	// Group. However Routes created by the group will inherit the
 	// Group's configuration.
 	//
+	// WithHost() constructs a new Group without a path prefix whose
+	// Routes only match requests for the given host. The host may
+	// contain named parameters such as ":tenant.example.com" whose
+	// values are added to the Params of the request. Routes without
+	// a host serve requests for any host not matched by a host
+	// pattern.
+	//
+	// Mount() constructs a new Route which passes requests for any
+	// method and any path below the prefix to an http.Handler, such as
+	// an http.FileServer or another mux. The handler sees the path
+	// with the prefix stripped, so a handler mounted at "/admin"
+	// receives "/admin/users" as "/users". Requests for the prefix
+	// without a trailing slash are not passed to the handler.
+	//
+	// MountRouter() places the Routes of another Router under the
+	// prefix, as though they had been created by a Group, so they
+	// are served and named along with the Routes of this
+	// RouteCreator. The Routes keep the configuration of the Router
+	// they were created by within the configuration the RouteCreator
+	// had when the Router was mounted: its middlewares run first,
+	// its error handler and timeout are used by Routes which have no
+	// error handler or the DefaultTimeout, and the Routes are
+	// restricted to its host.
+	// Building the routes fails if the mounted Router restricts them
+	// to another host. Changes to the mounted Router are seen
+	// whenever the routes are next built.
+	//
+	// Remove() removes a Route or Group constructed by this
+	// RouteCreator, returning false if it was not found. As with
+	// other configuration this does not change the routes which are
+	// already being served until their handler is rebuilt, as with
+	// SwappableHandler.Reload().
+	//
 	// The rest of the interface is syntactic sugar to make code more
 	// readable.
 	RouteCreator interface {
 		Routes() []Routable
+		Remove(Routable) bool
 
 		With([]Method, string, HandlerFunc) Route
 		WithHTTP([]Method, string, http.Handler) Route
 		WithGroup(string) Group
+		WithHost(string) Group
+		Mount(string, http.Handler) Route
+		MountRouter(string, Router) Group
 
 		Noop(string, HandlerFunc) Route
 		Connect(string, HandlerFunc) Route
@@ -131,31 +176,76 @@ type (
 	// Configuration. Note that Run may be called multiple times to
 	// serve the same set of routes on multiple addresses. Further
 	// configuration after the call to Run do not effect the served
-	// routes.
+	// routes. TLS Services negotiate HTTP/2 with clients which
+	// support it.
+	//
+	// RunWith() and RunTLSWith() serve the configured Routes in the
+	// same way using the passed ServerOptions.
 	Runnable interface {
 		Run(address string) (Service, error)
 		RunTLS(address string, cert string, key string) (Service, error)
+		RunWith(address string, options ServerOptions) (Service, error)
+		RunTLSWith(address string, cert string, key string, options ServerOptions) (Service, error)
 		Handler() (http.Handler, error)
 	}
 
 	// Service abstract an http.Server and provides
 	// methods for introspecting the service and
 	// stopping it from running.
+	//
+	// Stop() closes the listener immediately, leaving requests which
+	// are already being served to finish on their own.
+	//
+	// Shutdown() stops accepting connections and waits for active
+	// requests to finish. If the context expires first the remaining
+	// connections are closed and the error from the context is
+	// returned. IsRunning() is false once Shutdown() returns.
 	Service interface {
 		Address() string
 		IsRunning() bool
 		Stop() error
+		Shutdown(ctx context.Context) error
 		// Blocks until IsRunning() returns false
-		// Always returns an error with why the service stopped
+		// Always returns an error with why the service stopped,
+		// which is ErrServiceStopped after Stop() or Shutdown()
 		Wait() error
 	}
 
 	// A Router holds default configuration for all Routes and Groups
 	// and knows how to create them and run the final Server.
+	//
+	// URL() builds the path for the Route with the given name,
+	// including the prefixes of any Groups it is nested in. Each
+	// ":param" and "*catchall" segment is replaced by the escaped
+	// value for that name in the passed Params. Any constraint on a
+	// parameter, as in ":id<int>", is not checked.
+	//
+	// Walk() calls the passed function with a RouteInfo for every
+	// Route the Router would serve, after all Groups have been
+	// flattened, in the order they were created. Walking stops at the
+	// first error, which is returned.
+	//
+	// RouteTable() returns the RouteInfo for every Route in the same
+	// order as Walk().
 	Router interface {
 		RouteConfigurator
 		RouteCreator
 		Runnable
+
+		URL(name string, params Params) (string, error)
+		Walk(func(RouteInfo) error) error
+		RouteTable() ([]RouteInfo, error)
+	}
+
+	// RouteInfo describes a Route as it will be served by a Router.
+	RouteInfo struct {
+		Name            string
+		Methods         []Method
+		Host            string
+		Path            string
+		Timeout         time.Duration
+		Middlewares     int
+		HasErrorHandler bool
 	}
 
 	// A Group provides a container with a prefix for
@@ -171,12 +261,19 @@ type (
 	// which is used to flatten into an array
 	// of Routes which are then passed to
 	// the HandlerFactory.
+	//
+	// A Route may also be given a name with WithName() so that
+	// URLs for it can be built with Router.URL(). Names must be
+	// unique within a Router.
 	Route interface {
 		Routable
 
 		Prefix(string) Route
 		Methods() []Method
 		Handler() HandlerFunc
+
+		WithName(string) Route
+		Name() string
 	}
 
 	// HTTPError encapsulates an error with an HTTP Result code.
@@ -185,6 +282,26 @@ type (
 		Code() int
 	}
 
+	// PanicError is the HTTPError a HandlerFactory reports when a
+	// handler panics. It is an Internal Server Error which carries
+	// the value passed to panic and the stack of the panicking
+	// goroutine.
+	PanicError interface {
+		HTTPError
+		Value() interface{}
+		Stack() []byte
+	}
+
+	// CommitReporter is implemented by the http.ResponseWriter the
+	// HandlerFactory implementations in this repository hand to
+	// handlers. Committed reports whether the status and headers
+	// have already been sent, after which an error returned by the
+	// handler, or the expiry of its timeout, can no longer change
+	// the response.
+	CommitReporter interface {
+		Committed() bool
+	}
+
 	// HandlerFunc is a Routem Handler that takes a context and
 	// returns an HTTPError.  If the function returns a HTTPError
 	// Routem will write the error headers to the response. The
@@ -216,7 +333,11 @@ type (
 	// to the appropriate Route handler. Note that because this
 	// takes an array of Routes all groups have already been
 	// expanded into individual routes with the appropriate group
-	// prefix.
+	// prefix. A HandlerFactory must only dispatch to a Route with a
+	// Host() for requests to a matching host, with Routes without a
+	// host serving all other requests. A HandlerFactory which does
+	// not support a feature a Route uses, such as Host(), returns an
+	// error from Handler() rather than ignoring it.
 	HandlerFactory interface {
 		Handler([]Route) (http.Handler, error)
 	}
@@ -0,0 +1,145 @@
+package routem
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+)
+
+type (
+	// certificateLoader serves a key pair loaded from files, reloading
+	// it when the files change.
+	certificateLoader struct {
+		certFile string
+		keyFile  string
+		onError  func(error)
+
+		mu          sync.RWMutex
+		certificate *tls.Certificate
+		stamp       fileStamp
+	}
+
+	// fileStamp identifies the version of the certificate and key
+	// files which was last loaded.
+	fileStamp struct {
+		cert, key os.FileInfo
+	}
+)
+
+func newCertificateLoader(certFile, keyFile string, onError func(error)) (*certificateLoader, error) {
+	l := &certificateLoader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		onError:  onError,
+	}
+
+	stamp, err := l.stat()
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = l.load(stamp)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// GetCertificate returns the current certificate for a tls.Config.
+func (l *certificateLoader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.certificate, nil
+}
+
+// watch checks the files for changes every interval until done is
+// closed.
+func (l *certificateLoader) watch(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			l.reload()
+		}
+	}
+}
+
+// reload loads the key pair if either file has changed since it was
+// last loaded. A key pair which fails to load is reported to the
+// error hook and the current certificate is kept. It is not retried
+// until one of the files changes again, which happens when the
+// rotation of a pair was caught half way.
+func (l *certificateLoader) reload() {
+	stamp, err := l.stat()
+
+	if err == nil {
+		l.mu.RLock()
+		changed := !stamp.same(l.stamp)
+		l.mu.RUnlock()
+
+		if !changed {
+			return
+		}
+
+		err = l.load(stamp)
+	}
+
+	if err != nil && l.onError != nil {
+		l.onError(err)
+	}
+}
+
+// load reads the key pair, recording the stamp of the files taken
+// before reading them so any later change is picked up.
+func (l *certificateLoader) load(stamp fileStamp) error {
+	certificate, err := tls.LoadX509KeyPair(l.certFile, l.keyFile)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.stamp = stamp
+
+	if err != nil {
+		return err
+	}
+
+	l.certificate = &certificate
+
+	return nil
+}
+
+func (l *certificateLoader) stat() (fileStamp, error) {
+	cert, err := os.Stat(l.certFile)
+
+	if err != nil {
+		return fileStamp{}, err
+	}
+
+	key, err := os.Stat(l.keyFile)
+
+	if err != nil {
+		return fileStamp{}, err
+	}
+
+	return fileStamp{cert: cert, key: key}, nil
+}
+
+func (s fileStamp) same(other fileStamp) bool {
+	return sameFile(s.cert, other.cert) && sameFile(s.key, other.key)
+}
+
+func sameFile(a, b os.FileInfo) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return os.SameFile(a, b) && a.ModTime().Equal(b.ModTime()) && a.Size() == b.Size()
+}
@@ -0,0 +1,172 @@
+package routem
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	reloadAddress  = "localhost:9006"
+	reloadInterval = 10 * time.Millisecond
+)
+
+// writeKeyPair writes a new self signed certificate with the serial
+// number and its key to the files.
+func writeKeyPair(t *testing.T, certFile, keyFile string, serial int64) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.Nil(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.Nil(t, err)
+
+	require.Nil(t, ioutil.WriteFile(certFile,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.Nil(t, ioutil.WriteFile(keyFile,
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+}
+
+// keyPairFiles returns the names of certificate and key files in a
+// temporary directory.
+func keyPairFiles(t *testing.T) (string, string) {
+	dir := t.TempDir()
+	return filepath.Join(dir, "test.crt"), filepath.Join(dir, "test.key")
+}
+
+// servedSerial makes a request on a new connection and returns the
+// serial number of the certificate the server presented.
+func servedSerial(t *testing.T) int64 {
+	transport := &http.Transport{
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		DisableKeepAlives: true,
+	}
+
+	response, err := (&http.Client{Transport: transport}).Get("https://" + reloadAddress + "/")
+	require.Nil(t, err)
+	response.Body.Close()
+
+	return response.TLS.PeerCertificates[0].SerialNumber.Int64()
+}
+
+func TestServeTLSReloadsCertificate(t *testing.T) {
+	certFile, keyFile := keyPairFiles(t)
+	writeKeyPair(t, certFile, keyFile, 1)
+
+	srv, err := ServeTLSWith(reloadAddress, certFile, keyFile, protocolHandler(),
+		ServerOptions{ReloadInterval: reloadInterval})
+	require.Nil(t, err)
+	defer srv.Stop()
+
+	assert.Equal(t, int64(1), servedSerial(t))
+
+	// Make sure the modification time moves on
+	later := time.Now().Add(time.Second)
+	writeKeyPair(t, certFile, keyFile, 2)
+	require.Nil(t, os.Chtimes(certFile, later, later))
+
+	assert.Eventually(t, func() bool {
+		return servedSerial(t) == 2
+	}, time.Second, reloadInterval, "Certificate not reloaded")
+}
+
+func TestServeTLSReloadError(t *testing.T) {
+	certFile, keyFile := keyPairFiles(t)
+	writeKeyPair(t, certFile, keyFile, 1)
+
+	errors := make(chan error, 10)
+
+	srv, err := ServeTLSWith(reloadAddress, certFile, keyFile, protocolHandler(),
+		ServerOptions{
+			ReloadInterval: reloadInterval,
+			OnReloadError: func(err error) {
+				errors <- err
+			},
+		})
+	require.Nil(t, err)
+	defer srv.Stop()
+
+	require.Nil(t, ioutil.WriteFile(certFile, []byte("not a certificate"), 0600))
+
+	select {
+	case err := <-errors:
+		assert.NotNil(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Reload error not reported")
+	}
+
+	// The broken files are only reported once
+	select {
+	case err := <-errors:
+		t.Fatalf("Reload error reported again: %v", err)
+	case <-time.After(5 * reloadInterval):
+	}
+
+	assert.True(t, srv.IsRunning(), "Stopped by reload error")
+	assert.Equal(t, int64(1), servedSerial(t))
+}
+
+func TestServeTLSWithoutReload(t *testing.T) {
+	certFile, keyFile := keyPairFiles(t)
+	writeKeyPair(t, certFile, keyFile, 1)
+
+	srv, err := ServeTLS(reloadAddress, certFile, keyFile, protocolHandler())
+	require.Nil(t, err)
+	defer srv.Stop()
+
+	later := time.Now().Add(time.Second)
+	writeKeyPair(t, certFile, keyFile, 2)
+	require.Nil(t, os.Chtimes(certFile, later, later))
+
+	time.Sleep(5 * reloadInterval)
+	assert.Equal(t, int64(1), servedSerial(t))
+}
+
+func TestServeTLSGetCertificate(t *testing.T) {
+	certificate, err := tls.LoadX509KeyPair(testCert, testKey)
+	require.Nil(t, err)
+
+	srv, err := ServeTLSWith(reloadAddress, "", "", protocolHandler(),
+		ServerOptions{
+			GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+				return &certificate, nil
+			},
+		})
+	require.Nil(t, err)
+	defer srv.Stop()
+
+	assert.Equal(t, certificate.Leaf.SerialNumber.Int64(), servedSerial(t))
+}
+
+func TestServeTLSMissingFiles(t *testing.T) {
+	certFile, keyFile := keyPairFiles(t)
+
+	srv, err := ServeTLS(reloadAddress, certFile, keyFile, protocolHandler())
+	assert.NotNil(t, err, "Served without a certificate")
+	assert.Nil(t, srv, "Returned a service")
+}
@@ -9,6 +9,7 @@ type (
 		errorHandler ErrorHandlerFunc
 		timeout      time.Duration
 		middlewares  []MiddlewareFunc
+		host         string
 	}
 )
 
@@ -17,6 +18,7 @@ func newConfig(defs config) config {
 		timeout:      defs.timeout,
 		errorHandler: defs.errorHandler,
 		middlewares:  defs.middlewares,
+		host:         defs.host,
 	}
 }
 
@@ -61,3 +63,7 @@ func (c *config) ErrorHandler() ErrorHandlerFunc {
 func (c *config) Middlewares() []MiddlewareFunc {
 	return c.middlewares
 }
+
+func (c *config) Host() string {
+	return c.host
+}
@@ -16,6 +16,13 @@ type (
 		response http.ResponseWriter
 		params   Params
 	}
+
+	// rootedContext is the context of a request which falls back to
+	// the root context of a HandlerFactory for values.
+	rootedContext struct {
+		context.Context
+		root context.Context
+	}
 )
 
 const (
@@ -25,8 +32,43 @@ const (
 
 // NewRequestContext is a helper which a HandlerFactory can use to insert request, response and parameters into a context
 // before passing it to a route handler.
+//
+// The context is derived from the context of the request, so it is
+// cancelled when the client goes away and carries the values added by
+// the server and any net/http middleware in front of routem. Values
+// not found there are looked up in the root context c, which also
+// cancels the context when it is done. A timeout which is not
+// positive sets no deadline.
 func NewRequestContext(c context.Context, timeout time.Duration, request *http.Request, response http.ResponseWriter, params Params) (context.Context, context.CancelFunc) {
-	ctx, cancel := context.WithTimeout(c, timeout)
+	parent := c
+
+	if request != nil && request.Context() != c {
+		parent = rootedContext{Context: request.Context(), root: c}
+	}
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+
+	switch {
+	case timeout > 0:
+		ctx, cancel = context.WithTimeout(parent, timeout)
+	case parent != c && c.Done() != nil:
+		ctx, cancel = context.WithCancel(parent)
+	default:
+		// Nothing needs to be cancelled
+		ctx, cancel = parent, noCancel
+	}
+
+	if parent != c && c.Done() != nil {
+		done := ctx.Done()
+		go func() {
+			select {
+			case <-c.Done():
+				cancel()
+			case <-done:
+			}
+		}()
+	}
 
 	data := requestData{
 		request:  request,
@@ -39,6 +81,29 @@ func NewRequestContext(c context.Context, timeout time.Duration, request *http.R
 	return ctx, cancel
 }
 
+func noCancel() {}
+
+// withRequest replaces the request and response stored in a context
+// which already carries request data, keeping its Params.
+func withRequest(c context.Context, request *http.Request, response http.ResponseWriter) context.Context {
+	data, ok := c.Value(requestKey).(requestData)
+	if !ok {
+		contextPanic()
+	}
+
+	data.request = request
+	data.response = response
+
+	return context.WithValue(c, requestKey, data)
+}
+
+func (c rootedContext) Value(key interface{}) interface{} {
+	if value := c.Context.Value(key); value != nil {
+		return value
+	}
+	return c.root.Value(key)
+}
+
 // RequestFromContext returns the *http.Request stored in this Context
 func RequestFromContext(c context.Context) *http.Request {
 	val, ok := c.Value(requestKey).(requestData)
@@ -66,6 +131,22 @@ func ParamsFromContext(c context.Context) Params {
 	return val.params
 }
 
+// ParamsFromRequest returns the Params for a request passed to an
+// http.Handler by routem, as with a Route constructed by WithHTTP(),
+// or nil if the request was not passed on by routem.
+//
+// The context of such a request is the routem context for the Route,
+// so it also carries the deadline for the Route and any values added
+// by middleware, along with the cancellation and values of the
+// context the server gave the request.
+func ParamsFromRequest(request *http.Request) Params {
+	val, ok := request.Context().Value(requestKey).(requestData)
+	if !ok {
+		return nil
+	}
+	return val.params
+}
+
 func contextPanic() {
 	panic("Routem: WTF?! Missing request data in context!")
 }
@@ -77,3 +77,72 @@ func TestParamsFromContext(t *testing.T) {
 
 	assert.Panics(t, func() { ParamsFromContext(context.Background()) }, "No Panic on Empty Context")
 }
+
+func TestParamsFromRequest(t *testing.T) {
+	response, request, params := setupContextTest(t)
+	params["id"] = "42"
+
+	assert.Nil(t, ParamsFromRequest(request))
+
+	ctx, cancel := newContext(DefaultTimeout, request, response, params)
+	defer cancel()
+
+	assert.Equal(t, params, ParamsFromRequest(request.WithContext(ctx)))
+}
+
+func TestNewContextFromRequest(t *testing.T) {
+	response, request, params := setupContextTest(t)
+
+	type key int
+	root, cancelRoot := context.WithCancel(context.WithValue(context.Background(), key(0), "root"))
+	defer cancelRoot()
+
+	requestCtx, cancelRequest := context.WithCancel(context.WithValue(context.Background(), key(1), "request"))
+	request = request.WithContext(requestCtx)
+
+	ctx, cancel := NewRequestContext(root, DefaultTimeout, request, response, params)
+	defer cancel()
+
+	assert.Equal(t, "root", ctx.Value(key(0)))
+	assert.Equal(t, "request", ctx.Value(key(1)))
+
+	cancelRequest()
+	<-ctx.Done()
+	assert.Equal(t, context.Canceled, ctx.Err())
+}
+
+func TestNewContextRootCancel(t *testing.T) {
+	response, request, params := setupContextTest(t)
+
+	root, cancelRoot := context.WithCancel(context.Background())
+
+	ctx, cancel := NewRequestContext(root, DefaultTimeout, request, response, params)
+	defer cancel()
+
+	cancelRoot()
+	<-ctx.Done()
+	assert.Equal(t, context.Canceled, ctx.Err())
+}
+
+func TestNewContextNoTimeout(t *testing.T) {
+	response, request, params := setupContextTest(t)
+
+	ctx, cancel := NewRequestContext(context.Background(), NoTimeout, request, response, params)
+	_, hasDeadline := ctx.Deadline()
+	assert.False(t, hasDeadline)
+	assert.Nil(t, ctx.Err())
+	cancel()
+
+	// The root context still cancels the request
+	root, cancelRoot := context.WithCancel(context.Background())
+
+	ctx, cancel = NewRequestContext(root, NoTimeout, request, response, params)
+	defer cancel()
+
+	_, hasDeadline = ctx.Deadline()
+	assert.False(t, hasDeadline)
+
+	cancelRoot()
+	<-ctx.Done()
+	assert.Equal(t, context.Canceled, ctx.Err())
+}
@@ -2,6 +2,7 @@ package routem
 
 import (
 	"net/http"
+	"strings"
 )
 
 type (
@@ -39,6 +40,48 @@ func (c *creator) WithGroup(path string) Group {
 	return group
 }
 
+func (c *creator) WithHost(host string) Group {
+	group := newGroup(c.config, "")
+	group.host = host
+
+	c.routes = append(c.routes, group)
+
+	return group
+}
+
+func (c *creator) Mount(prefix string, handler http.Handler) Route {
+	route := newRoute(c.config, AnyMethod, mountPath(prefix), wrapMountedHandler(handler))
+
+	c.routes = append(c.routes, route)
+
+	return route
+}
+
+func (c *creator) MountRouter(prefix string, router Router) Group {
+	outer := newConfig(c.config)
+	outer.middlewares = append([]MiddlewareFunc{}, c.middlewares...)
+
+	group := &mountedRouter{
+		Router: router,
+		prefix: strings.TrimSuffix(prefix, "/"),
+		outer:  outer,
+	}
+
+	c.routes = append(c.routes, group)
+
+	return group
+}
+
+func (c *creator) Remove(routable Routable) bool {
+	for i, existing := range c.routes {
+		if existing == routable {
+			c.routes = append(c.routes[:i:i], c.routes[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
 // =-=-=-=-=-=-=-=-=-=
 // HandlerFunc Aliases
 // =-=-=-=-=-=-=-=-=-=
@@ -1,10 +1,31 @@
 package routem
 
+import (
+	"fmt"
+	"net/http"
+)
+
+// ErrHandlerTimeout is returned by the http.ResponseWriter of a
+// request when a handler writes after the timeout for its route has
+// expired. It is the same error used by http.TimeoutHandler.
+var ErrHandlerTimeout = http.ErrHandlerTimeout
+
+// ErrServiceStopped is returned by Service.Wait() when the Service
+// was stopped with Stop() or Shutdown(). It is the same error
+// returned by http.Server once it has been closed.
+var ErrServiceStopped = http.ErrServerClosed
+
 type (
 	httpError struct {
 		code int
 		err  error
 	}
+
+	panicError struct {
+		httpError
+		value interface{}
+		stack []byte
+	}
 )
 
 func (e *httpError) Code() int {
@@ -22,3 +43,24 @@ func NewHTTPError(code int, err error) HTTPError {
 		err:  err,
 	}
 }
+
+func (e *panicError) Value() interface{} {
+	return e.value
+}
+
+func (e *panicError) Stack() []byte {
+	return e.stack
+}
+
+// NewPanicError constructs a new PanicError for a recovered panic
+// value and the stack trace at the time of the panic.
+func NewPanicError(value interface{}, stack []byte) PanicError {
+	return &panicError{
+		httpError: httpError{
+			code: http.StatusInternalServerError,
+			err:  fmt.Errorf("Panic: %v", value),
+		},
+		value: value,
+		stack: stack,
+	}
+}
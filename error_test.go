@@ -15,3 +15,13 @@ func TestNewHTTPError(t *testing.T) {
 	assert.Equal(t, err.Error(), httpErr.Error())
 	assert.Equal(t, httpErr.Code(), http.StatusBadRequest)
 }
+
+func TestNewPanicError(t *testing.T) {
+	stack := []byte("stack")
+	panicErr := NewPanicError("boom", stack)
+
+	assert.Equal(t, http.StatusInternalServerError, panicErr.Code())
+	assert.Equal(t, "boom", panicErr.Value())
+	assert.Equal(t, stack, panicErr.Stack())
+	assert.Contains(t, panicErr.Error(), "boom")
+}
@@ -305,3 +305,39 @@ func TestAnyHTTP(t *testing.T) {
 
 	assertTestHTTPRouteWithMethods(t, route, testHandler, AnyMethod)
 }
+
+func TestWithHost(t *testing.T) {
+	group := newGroup(testConfig(), testPathTwo)
+
+	hostGroup := group.WithHost(":tenant.example.com")
+
+	assertTestConfig(t, hostGroup)
+	assert.Equal(t, "", hostGroup.Path(), "Wrong path")
+	assert.Equal(t, ":tenant.example.com", hostGroup.Host(), "Wrong host")
+	assert.Equal(t, "", group.Host(), "Host leaked to parent")
+
+	route := hostGroup.Get(testPath, testHandler)
+	assert.Equal(t, ":tenant.example.com", route.Host(), "Route didn't inherit host")
+	assert.Equal(t, ":tenant.example.com", route.Prefix("/").Host(), "Prefix lost host")
+
+	subGroup := hostGroup.WithGroup(testPath)
+	assert.Equal(t, ":tenant.example.com", subGroup.Host(), "Sub group didn't inherit host")
+}
+
+func TestRemove(t *testing.T) {
+	group := newGroup(testConfig(), testPathTwo)
+
+	first := group.Get(testPath, testHandler)
+	subGroup := group.WithGroup(testPath)
+	last := group.Put(testPath, testHandler)
+
+	assert.True(t, group.Remove(subGroup), "Didn't remove group")
+	assert.Equal(t, []Routable{first, last}, group.Routes(), "Wrong routes")
+
+	assert.False(t, group.Remove(subGroup), "Removed group twice")
+	assert.False(t, subGroup.Remove(first), "Removed from the wrong creator")
+
+	assert.True(t, group.Remove(first), "Didn't remove route")
+	assert.True(t, group.Remove(last), "Didn't remove route")
+	assert.Empty(t, group.Routes(), "Routes left")
+}
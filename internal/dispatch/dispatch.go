@@ -0,0 +1,214 @@
+// Package dispatch runs routem route handlers on behalf of the
+// HandlerFactory implementations in this repository so they all
+// treat timeouts, panics and errors the same way.
+package dispatch
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync"
+
+	"github.com/nick-codes/routem"
+
+	"golang.org/x/net/context"
+)
+
+type (
+	// A Dispatcher runs route handlers and passes their errors to
+	// the error handlers.
+	Dispatcher struct {
+		// Context is the root context for every request
+		Context context.Context
+		// ErrorHandler is used when a route has no error handler
+		ErrorHandler routem.ErrorHandlerFunc
+		// PanicHandler is called with every recovered panic
+		PanicHandler func(routem.PanicError, context.Context)
+	}
+
+	// runState is the state Run needs for each request, which is
+	// pooled unless the handler times out.
+	runState struct {
+		writer   timeoutWriter
+		complete chan routem.HTTPError
+	}
+
+	// routingError is an error produced by a HandlerFactory rather
+	// than a route handler.
+	routingError struct {
+		routem.HTTPError
+	}
+)
+
+// Errors reported by HandlerFactories for requests which can not be
+// routed or which time out.
+var (
+	RouteNotFound    = NewRoutingError(http.StatusNotFound, "No Such Route")
+	MethodNotAllowed = NewRoutingError(http.StatusMethodNotAllowed, "Method Not Allowed")
+	RequestTimeout   = routem.NewHTTPError(http.StatusRequestTimeout, fmt.Errorf("Request Timed Out!"))
+)
+
+// aborted is sent in place of the error of a handler which panicked
+// with http.ErrAbortHandler.
+var aborted = routem.NewHTTPError(http.StatusInternalServerError, http.ErrAbortHandler)
+
+var runPool = sync.Pool{
+	New: func() interface{} {
+		return &runState{
+			writer:   timeoutWriter{header: make(http.Header)},
+			complete: make(chan routem.HTTPError, 1),
+		}
+	},
+}
+
+// NewRoutingError constructs an HTTPError for a request which can not
+// be routed. Without an error handler the code and message of a
+// routing error are sent to the client, where any other error
+// results in an Internal Server Error.
+func NewRoutingError(code int, message string) routem.HTTPError {
+	return &routingError{routem.NewHTTPError(code, fmt.Errorf("%s", message))}
+}
+
+// Handler builds the middleware stack for a route.
+func Handler(route routem.Route) routem.HandlerFunc {
+	handler := route.Handler()
+	middlewares := route.Middlewares()
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// Run executes a handler for a route in its own goroutine so the
+// timeout for the route can be enforced. It returns the error from
+// the handler and, when the route timed out, whether the handler had
+// already committed the response. A route without a timeout, see
+// routem.NoTimeout, is run on the calling goroutine instead.
+//
+// Once the context of the route is done writes to the response fail
+// with routem.ErrHandlerTimeout and RequestTimeout is returned, even
+// if the handler returns before the dispatcher notices. The response
+// writer handed to the handler is a routem.CommitReporter, supports
+// http.Hijacker and unwraps to the real response for
+// http.ResponseController.
+//
+// A panic in the handler is recovered and returned as a
+// routem.PanicError, except for http.ErrAbortHandler which is
+// panicked again by Run so net/http aborts the response.
+//
+// The writer is pooled, so as with any http.ResponseWriter it must not
+// be used once the handler has returned.
+func (d *Dispatcher) Run(route routem.Route, handler routem.HandlerFunc, request *http.Request, response http.ResponseWriter, params routem.Params) (routem.HTTPError, bool) {
+	state := runPool.Get().(*runState)
+	writer := &state.writer
+	writer.reset(response)
+
+	ctx, cancel := routem.NewRequestContext(d.Context, route.Timeout(), request, writer, params)
+	writer.ctx = ctx
+
+	defer cancel()
+
+	if route.Timeout() <= 0 {
+		return d.runInline(state, handler, ctx), false
+	}
+
+	go func() {
+		// Panics in this goroutine are not recovered by net/http
+		defer func() {
+			if recovered := recover(); recovered == http.ErrAbortHandler {
+				state.complete <- aborted
+			} else if recovered != nil {
+				panicErr := routem.NewPanicError(recovered, debug.Stack())
+				if d.PanicHandler != nil {
+					d.PanicHandler(panicErr, ctx)
+				}
+				state.complete <- panicErr
+			}
+		}()
+		state.complete <- handler(ctx)
+	}()
+
+	select {
+	case <-ctx.Done():
+		// The handler may still be using the state so it is not
+		// returned to the pool
+		return RequestTimeout, writer.timeout()
+	case err := <-state.complete:
+		timedOut := writer.finish()
+
+		writer.reset(nil)
+		runPool.Put(state)
+
+		if err == aborted {
+			panic(http.ErrAbortHandler)
+		}
+		if timedOut {
+			return RequestTimeout, false
+		}
+		return err, false
+	}
+}
+
+// runInline runs a handler on the calling goroutine, where net/http
+// recovers http.ErrAbortHandler itself.
+func (d *Dispatcher) runInline(state *runState, handler routem.HandlerFunc, ctx context.Context) (err routem.HTTPError) {
+	writer := &state.writer
+
+	defer func() {
+		recovered := recover()
+		if recovered != nil && recovered != http.ErrAbortHandler {
+			panicErr := routem.NewPanicError(recovered, debug.Stack())
+			if d.PanicHandler != nil {
+				d.PanicHandler(panicErr, ctx)
+			}
+			err = panicErr
+		}
+
+		// The client may have gone away before the response began
+		if writer.finish() {
+			err = RequestTimeout
+		}
+
+		writer.reset(nil)
+		runPool.Put(state)
+
+		if recovered == http.ErrAbortHandler {
+			panic(recovered)
+		}
+	}()
+
+	return handler(ctx)
+}
+
+// Fail passes an error to the error handler for the route, which may
+// be nil, falling back to the ErrorHandler of the Dispatcher and then
+// to a plain error response.
+func (d *Dispatcher) Fail(response http.ResponseWriter, request *http.Request, route routem.Route, err routem.HTTPError, params routem.Params) {
+	timeout := routem.DefaultTimeout
+	if route != nil {
+		timeout = route.Timeout()
+	}
+
+	ctx, cancel := routem.NewRequestContext(d.Context, timeout, request, response, params)
+
+	defer cancel()
+
+	var errErr error
+
+	if _, routing := err.(*routingError); route != nil && route.ErrorHandler() != nil {
+		errErr = route.ErrorHandler()(err, ctx)
+	} else if d.ErrorHandler != nil {
+		errErr = d.ErrorHandler(err, ctx)
+	} else if routing {
+		http.Error(response, err.Error(), err.Code())
+	} else {
+		errErr = err
+	}
+
+	if _, panicked := errErr.(routem.PanicError); panicked {
+		// The panic value is for the PanicHandler, not the client
+		http.Error(response, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else if errErr != nil {
+		http.Error(response, fmt.Sprintf("Internal Server Error: %s", errErr), http.StatusInternalServerError)
+	}
+}
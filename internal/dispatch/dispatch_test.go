@@ -0,0 +1,251 @@
+package dispatch
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/nick-codes/routem"
+
+	"golang.org/x/net/context"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRequest(t *testing.T) *http.Request {
+	request, err := http.NewRequest("GET", "http://localhost/test", nil)
+	require.Nil(t, err)
+	return request
+}
+
+func TestHandlerMiddlewareOrder(t *testing.T) {
+	var order []string
+	middleware := func(name string) routem.MiddlewareFunc {
+		return func(next routem.HandlerFunc) routem.HandlerFunc {
+			return func(ctx context.Context) routem.HTTPError {
+				order = append(order, name)
+				return next(ctx)
+			}
+		}
+	}
+
+	route := routem.NewRouter(nil).Get("/test", func(ctx context.Context) routem.HTTPError {
+		order = append(order, "handler")
+		return nil
+	})
+	route.WithMiddleware(middleware("first")).WithMiddleware(middleware("second"))
+
+	assert.Nil(t, Handler(route)(context.Background()))
+	assert.Equal(t, []string{"first", "second", "handler"}, order)
+}
+
+func TestRunReturnsError(t *testing.T) {
+	route := routem.NewRouter(nil).Get("/test", func(ctx context.Context) routem.HTTPError {
+		return routem.NewHTTPError(http.StatusTeapot, fmt.Errorf("Teapot"))
+	})
+
+	d := &Dispatcher{Context: context.Background()}
+	err, committed := d.Run(route, route.Handler(), testRequest(t), httptest.NewRecorder(), nil)
+
+	require.NotNil(t, err)
+	assert.Equal(t, http.StatusTeapot, err.Code())
+	assert.False(t, committed)
+}
+
+func TestRunRecoversPanic(t *testing.T) {
+	var handled routem.PanicError
+	route := routem.NewRouter(nil).Get("/test", func(ctx context.Context) routem.HTTPError {
+		panic("boom")
+	})
+
+	d := &Dispatcher{
+		Context: context.Background(),
+		PanicHandler: func(err routem.PanicError, ctx context.Context) {
+			handled = err
+		},
+	}
+	err, _ := d.Run(route, route.Handler(), testRequest(t), httptest.NewRecorder(), nil)
+
+	require.NotNil(t, handled)
+	assert.Equal(t, handled, err)
+	assert.Equal(t, "boom", handled.Value())
+}
+
+func TestRunAbortHandler(t *testing.T) {
+	handled := false
+	route := routem.NewRouter(nil).Get("/test", func(ctx context.Context) routem.HTTPError {
+		panic(http.ErrAbortHandler)
+	})
+
+	d := &Dispatcher{
+		Context: context.Background(),
+		PanicHandler: func(err routem.PanicError, ctx context.Context) {
+			handled = true
+		},
+	}
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		d.Run(route, route.Handler(), testRequest(t), httptest.NewRecorder(), nil)
+	})
+	assert.False(t, handled)
+}
+
+func TestRunInline(t *testing.T) {
+	route := routem.NewRouter(nil).Get("/test", func(ctx context.Context) routem.HTTPError {
+		_, hasDeadline := ctx.Deadline()
+		assert.False(t, hasDeadline)
+		routem.ResponseWriterFromContext(ctx).Header().Set("X-Inline", "true")
+		return routem.NewHTTPError(http.StatusTeapot, fmt.Errorf("Teapot"))
+	})
+	route.WithTimeout(routem.NoTimeout)
+
+	d := &Dispatcher{Context: context.Background()}
+	response := httptest.NewRecorder()
+	err, committed := d.Run(route, route.Handler(), testRequest(t), response, nil)
+
+	require.NotNil(t, err)
+	assert.Equal(t, http.StatusTeapot, err.Code())
+	assert.False(t, committed)
+	assert.Equal(t, "true", response.Header().Get("X-Inline"))
+}
+
+func TestRunInlinePanics(t *testing.T) {
+	var handled routem.PanicError
+	route := routem.NewRouter(nil).Get("/test", func(ctx context.Context) routem.HTTPError {
+		panic("boom")
+	})
+	route.WithTimeout(routem.NoTimeout)
+
+	d := &Dispatcher{
+		Context: context.Background(),
+		PanicHandler: func(err routem.PanicError, ctx context.Context) {
+			handled = err
+		},
+	}
+	err, _ := d.Run(route, route.Handler(), testRequest(t), httptest.NewRecorder(), nil)
+
+	require.NotNil(t, handled)
+	assert.Equal(t, handled, err)
+
+	aborting := routem.NewRouter(nil).Get("/test", func(ctx context.Context) routem.HTTPError {
+		panic(http.ErrAbortHandler)
+	})
+	aborting.WithTimeout(routem.NoTimeout)
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		d.Run(aborting, aborting.Handler(), testRequest(t), httptest.NewRecorder(), nil)
+	})
+}
+
+func TestRunInlineClientGone(t *testing.T) {
+	route := routem.NewRouter(nil).Get("/test", func(ctx context.Context) routem.HTTPError {
+		<-ctx.Done()
+		return nil
+	})
+	route.WithTimeout(routem.NoTimeout)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	d := &Dispatcher{Context: context.Background()}
+	err, committed := d.Run(route, route.Handler(), testRequest(t).WithContext(ctx), httptest.NewRecorder(), nil)
+
+	assert.Equal(t, RequestTimeout, err)
+	assert.False(t, committed)
+}
+
+func TestRunTimeout(t *testing.T) {
+	done := make(chan struct{})
+	route := routem.NewRouter(nil).Get("/test", func(ctx context.Context) routem.HTTPError {
+		<-ctx.Done()
+		close(done)
+		return nil
+	})
+	route.WithTimeout(10 * time.Millisecond)
+
+	d := &Dispatcher{Context: context.Background()}
+	err, committed := d.Run(route, route.Handler(), testRequest(t), httptest.NewRecorder(), nil)
+
+	assert.Equal(t, RequestTimeout, err)
+	assert.False(t, committed)
+	<-done
+}
+
+func TestFailRoutingError(t *testing.T) {
+	d := &Dispatcher{Context: context.Background()}
+	response := httptest.NewRecorder()
+	d.Fail(response, testRequest(t), nil, RouteNotFound, nil)
+
+	assert.Equal(t, http.StatusNotFound, response.Code)
+	assert.Equal(t, "No Such Route\n", response.Body.String())
+}
+
+func TestFailHandlerError(t *testing.T) {
+	d := &Dispatcher{Context: context.Background()}
+	response := httptest.NewRecorder()
+	d.Fail(response, testRequest(t), nil, routem.NewHTTPError(http.StatusTeapot, fmt.Errorf("Teapot")), nil)
+
+	assert.Equal(t, http.StatusInternalServerError, response.Code)
+}
+
+func TestFailPanicValueHidden(t *testing.T) {
+	d := &Dispatcher{Context: context.Background()}
+	response := httptest.NewRecorder()
+	d.Fail(response, testRequest(t), nil, routem.NewPanicError("secret", nil), nil)
+
+	assert.Equal(t, http.StatusInternalServerError, response.Code)
+	assert.Equal(t, "Internal Server Error\n", response.Body.String())
+}
+
+func TestFailErrorHandlerPrecedence(t *testing.T) {
+	var called string
+	route := routem.NewRouter(nil).Get("/test", nil)
+	route.WithErrorHandler(func(err routem.HTTPError, ctx context.Context) error {
+		called = "route"
+		return nil
+	})
+
+	d := &Dispatcher{
+		Context: context.Background(),
+		ErrorHandler: func(err routem.HTTPError, ctx context.Context) error {
+			called = "dispatcher"
+			return nil
+		},
+	}
+
+	d.Fail(httptest.NewRecorder(), testRequest(t), route, RouteNotFound, nil)
+	assert.Equal(t, "route", called)
+
+	d.Fail(httptest.NewRecorder(), testRequest(t), nil, RouteNotFound, nil)
+	assert.Equal(t, "dispatcher", called)
+}
+
+func TestRunPooledStateReset(t *testing.T) {
+	first := true
+	route := routem.NewRouter(nil).Get("/test", func(ctx context.Context) routem.HTTPError {
+		response := routem.ResponseWriterFromContext(ctx)
+		if first {
+			response.Header().Set("X-First", "true")
+			response.WriteHeader(http.StatusTeapot)
+		}
+		return nil
+	})
+
+	d := &Dispatcher{Context: context.Background()}
+	for _, code := range []int{http.StatusTeapot, http.StatusOK} {
+		response := httptest.NewRecorder()
+		err, _ := d.Run(route, route.Handler(), testRequest(t), response, nil)
+		first = false
+
+		assert.Nil(t, err)
+		assert.Equal(t, code, response.Code)
+	}
+
+	response := httptest.NewRecorder()
+	d.Run(route, route.Handler(), testRequest(t), response, nil)
+	assert.Equal(t, "", response.Header().Get("X-First"), "Headers leaked between requests")
+}
@@ -0,0 +1,66 @@
+package dispatch
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nick-codes/routem"
+)
+
+// CheckRoute reports the problems every HandlerFactory in this
+// repository rejects a route for: being nil or having a path which is
+// empty, does not begin with a slash or contains an empty segment.
+func CheckRoute(route routem.Route) error {
+	if route == nil {
+		return fmt.Errorf("Received a nil route.")
+	}
+
+	path := route.Path()
+
+	if len(path) == 0 {
+		return fmt.Errorf("Received a zero length path.")
+	}
+
+	if strings.Contains(path, "//") {
+		return fmt.Errorf("Route contains an invalid path: %s", path)
+	}
+
+	if !strings.HasPrefix(path, "/") {
+		return fmt.Errorf("Route does not begin with a slash: %s", path)
+	}
+
+	return nil
+}
+
+// CheckNoHost reports an error for a route with a Host(), for the
+// HandlerFactories which do not support host routing.
+func CheckNoHost(route routem.Route) error {
+	if len(route.Host()) > 0 {
+		return fmt.Errorf("Host routing is not supported: %s%s", route.Host(), route.Path())
+	}
+	return nil
+}
+
+// AllowHeader builds the sorted value of the Allow header for a path
+// which only has routes for other methods, which must not be empty,
+// adding any extra methods which are answered without a route. It
+// also returns the value for the first method with a route, whose
+// route handles the MethodNotAllowed error.
+func AllowHeader[T any](allowed map[routem.Method]T, extra ...routem.Method) (string, T) {
+	methods := make([]string, 0, len(allowed)+len(extra))
+	for method := range allowed {
+		methods = append(methods, string(method))
+	}
+	sort.Strings(methods)
+	first := allowed[routem.Method(methods[0])]
+
+	for _, method := range extra {
+		if _, exists := allowed[method]; !exists {
+			methods = append(methods, string(method))
+		}
+	}
+	sort.Strings(methods)
+
+	return strings.Join(methods, ", "), first
+}
@@ -0,0 +1,39 @@
+package dispatch
+
+import (
+	"github.com/nick-codes/routem"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckRoute(t *testing.T) {
+	router := routem.NewRouter(nil)
+
+	assert.Nil(t, CheckRoute(router.Get("/users/:id", nil)))
+	assert.NotNil(t, CheckRoute(nil))
+
+	for _, path := range []string{"", "users", "/users//id"} {
+		assert.NotNil(t, CheckRoute(router.Get(path, nil)), path)
+	}
+}
+
+func TestCheckNoHost(t *testing.T) {
+	router := routem.NewRouter(nil)
+
+	assert.Nil(t, CheckNoHost(router.Get("/test", nil)))
+	assert.NotNil(t, CheckNoHost(router.WithHost("example.com").Get("/test", nil)))
+}
+
+func TestAllowHeader(t *testing.T) {
+	allowed := map[routem.Method]string{
+		routem.Put:  "put",
+		routem.Get:  "get",
+		routem.Head: "head",
+	}
+
+	allow, first := AllowHeader(allowed, routem.Head, routem.Options)
+	assert.Equal(t, "GET, HEAD, OPTIONS, PUT", allow)
+	assert.Equal(t, "get", first)
+}
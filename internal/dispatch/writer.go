@@ -0,0 +1,179 @@
+package dispatch
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/nick-codes/routem"
+
+	"golang.org/x/net/context"
+)
+
+// Compile time type assertions
+var _ http.ResponseWriter = &timeoutWriter{}
+var _ http.Flusher = &timeoutWriter{}
+var _ http.Hijacker = &timeoutWriter{}
+var _ routem.CommitReporter = &timeoutWriter{}
+
+// timeoutWriter guards the http.ResponseWriter handed to a route
+// handler. Once the context of the route is done every further write
+// fails with routem.ErrHandlerTimeout so a late handler can not
+// corrupt the error response or race the server.
+//
+// Headers are kept apart from the real response until the handler
+// commits the response, leaving the real headers to the error path
+// when a timeout happens first.
+type timeoutWriter struct {
+	response http.ResponseWriter
+	header   http.Header
+	ctx      context.Context
+
+	mu          sync.Mutex
+	timedOut    bool
+	wroteHeader bool
+}
+
+func newTimeoutWriter(response http.ResponseWriter) *timeoutWriter {
+	return &timeoutWriter{
+		response: response,
+		header:   make(http.Header),
+	}
+}
+
+// reset prepares a pooled writer for the next response.
+func (tw *timeoutWriter) reset(response http.ResponseWriter) {
+	tw.response = response
+	tw.ctx = nil
+	tw.timedOut = false
+	tw.wroteHeader = false
+	clear(tw.header)
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.expired() || tw.wroteHeader {
+		return
+	}
+	tw.writeHeader(code)
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.expired() {
+		return 0, routem.ErrHandlerTimeout
+	}
+	if !tw.wroteHeader {
+		tw.writeHeader(http.StatusOK)
+	}
+	return tw.response.Write(b)
+}
+
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.expired() {
+		return
+	}
+	if flusher, ok := tw.response.(http.Flusher); ok {
+		if !tw.wroteHeader {
+			tw.writeHeader(http.StatusOK)
+		}
+		flusher.Flush()
+	}
+}
+
+// Hijack hands the connection over to the handler, as for WebSocket
+// upgrades, which commits the response.
+func (tw *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.expired() {
+		return nil, nil, routem.ErrHandlerTimeout
+	}
+
+	hijacker, ok := tw.response.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err == nil {
+		tw.wroteHeader = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the real response for http.ResponseController.
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter {
+	return tw.response
+}
+
+// Committed reports whether the status and headers have already been
+// sent on the real response.
+func (tw *timeoutWriter) Committed() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	return tw.wroteHeader
+}
+
+// timeout stops all further writes and reports whether the response
+// was already committed.
+func (tw *timeoutWriter) timeout() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	tw.timedOut = true
+	return tw.wroteHeader
+}
+
+// finish hands any headers set by a completed handler which did not
+// write a response over to the real response. It reports whether the
+// context was done before the handler committed the response, in
+// which case the handler timed out even though it returned.
+func (tw *timeoutWriter) finish() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.wroteHeader {
+		return false
+	}
+	if tw.expired() {
+		tw.timedOut = true
+		return true
+	}
+	copyHeader(tw.response.Header(), tw.header)
+	return false
+}
+
+// expired reports whether writes should fail. The context is checked
+// as well as timedOut since a handler can see the context is done
+// before the dispatcher gets to call timeout(). Must be called with
+// the lock held.
+func (tw *timeoutWriter) expired() bool {
+	return tw.timedOut || (tw.ctx != nil && tw.ctx.Err() != nil)
+}
+
+func (tw *timeoutWriter) writeHeader(code int) {
+	copyHeader(tw.response.Header(), tw.header)
+	tw.wroteHeader = true
+	tw.response.WriteHeader(code)
+}
+
+func copyHeader(dst, src http.Header) {
+	for key, values := range src {
+		dst[key] = values
+	}
+}
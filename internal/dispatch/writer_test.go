@@ -0,0 +1,106 @@
+package dispatch
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/nick-codes/routem"
+
+	"golang.org/x/net/context"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriterRefusesWritesOnceContextDone(t *testing.T) {
+	response := httptest.NewRecorder()
+	writer := newTimeoutWriter(response)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	writer.ctx = ctx
+	cancel()
+
+	// The dispatcher has not called timeout() yet
+	writer.Header().Set("X-Late", "true")
+	writer.WriteHeader(http.StatusTeapot)
+	_, err := writer.Write([]byte("late"))
+	writer.Flush()
+
+	assert.Equal(t, routem.ErrHandlerTimeout, err)
+	assert.False(t, writer.Committed())
+	assert.True(t, writer.finish(), "Completed handler not timed out")
+	assert.Equal(t, "", response.Header().Get("X-Late"))
+	assert.False(t, response.Code == http.StatusTeapot)
+}
+
+func TestWriterHijackNotSupported(t *testing.T) {
+	writer := newTimeoutWriter(httptest.NewRecorder())
+
+	_, _, err := writer.Hijack()
+
+	assert.Equal(t, http.ErrNotSupported, err)
+	assert.False(t, writer.Committed())
+}
+
+// serve runs a handler through a Dispatcher behind a real server.
+func serve(t *testing.T, handler routem.HandlerFunc) *httptest.Server {
+	route := routem.NewRouter(nil).Get("/test", handler)
+	d := &Dispatcher{Context: context.Background()}
+
+	return httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		if err, committed := d.Run(route, route.Handler(), request, response, nil); err != nil && !committed {
+			d.Fail(response, request, route, err, nil)
+		}
+	}))
+}
+
+func TestWriterHijack(t *testing.T) {
+	server := serve(t, func(ctx context.Context) routem.HTTPError {
+		conn, rw, err := routem.ResponseWriterFromContext(ctx).(http.Hijacker).Hijack()
+		if err != nil {
+			return routem.NewHTTPError(http.StatusInternalServerError, err)
+		}
+		defer conn.Close()
+
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n\r\nhijacked")
+		rw.Flush()
+		return nil
+	})
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	require.Nil(t, err)
+	defer conn.Close()
+
+	fmt.Fprint(conn, "GET /test HTTP/1.1\r\nHost: localhost\r\n\r\n")
+
+	response, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, response.StatusCode)
+}
+
+func TestWriterUnwrap(t *testing.T) {
+	server := serve(t, func(ctx context.Context) routem.HTTPError {
+		controller := http.NewResponseController(routem.ResponseWriterFromContext(ctx))
+
+		// Only supported by the real response
+		err := controller.SetWriteDeadline(time.Now().Add(time.Minute))
+		if err != nil {
+			return routem.NewHTTPError(http.StatusInternalServerError, err)
+		}
+		return nil
+	})
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/test")
+	require.Nil(t, err)
+	response.Body.Close()
+
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+}
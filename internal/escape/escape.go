@@ -0,0 +1,72 @@
+// Package escape canonicalizes escaped request paths so that
+// HandlerFactory implementations can match them against routes with
+// static segments escaped the same way.
+package escape
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Path returns the escaped path of a URL with each segment escaped
+// the way url.PathEscape would. Most paths need no escaping and are
+// returned as they are.
+func Path(u *url.URL) string {
+	if len(u.RawPath) == 0 && isCanonical(u.Path) {
+		return u.Path
+	}
+
+	segments := strings.Split(u.EscapedPath(), "/")
+	for i, segment := range segments {
+		if decoded, err := url.PathUnescape(segment); err == nil {
+			segments[i] = url.PathEscape(decoded)
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// Segment escapes static text from the path of a route the same way
+// Path escapes each segment of a request.
+func Segment(segment string) string {
+	return url.PathEscape(segment)
+}
+
+// Unescape decodes a single escaped segment, returning it unchanged
+// if it is not validly escaped.
+func Unescape(value string) string {
+	if strings.IndexByte(value, '%') < 0 {
+		return value
+	}
+	if decoded, err := url.PathUnescape(value); err == nil {
+		return decoded
+	}
+	return value
+}
+
+// UnescapeSegments decodes each segment of an escaped value which
+// may span several segments.
+func UnescapeSegments(value string) string {
+	if strings.IndexByte(value, '%') < 0 {
+		return value
+	}
+	segments := strings.Split(value, "/")
+	for i, segment := range segments {
+		segments[i] = Unescape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// isCanonical reports whether url.PathEscape would leave every
+// segment of the path unchanged.
+func isCanonical(path string) bool {
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case strings.IndexByte("-_.~$&+:=@/", c) >= 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,48 @@
+package escape
+
+import (
+	"net/url"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPath(t *testing.T) {
+	cases := []struct {
+		url  string
+		path string
+	}{
+		{"/users/42", "/users/42"},
+		{"/a:b/c@d/$e&f+g=h", "/a:b/c@d/$e&f+g=h"},
+		{"/hello%20world", "/hello%20world"},
+		{"/hello world", "/hello%20world"},
+		{"/files/a%2fb", "/files/a%2Fb"},
+		{"/files/%7Euser", "/files/~user"},
+		{"/files/%3A", "/files/:"},
+	}
+
+	for _, c := range cases {
+		u, err := url.Parse(c.url)
+		require.Nil(t, err, c.url)
+		assert.Equal(t, c.path, Path(u), c.url)
+	}
+}
+
+func TestPathInvalidRawPath(t *testing.T) {
+	u := &url.URL{Path: "/files/%zz", RawPath: "/files/%zz"}
+	assert.Equal(t, "/files/%25zz", Path(u))
+}
+
+func TestUnescape(t *testing.T) {
+	assert.Equal(t, "plain", Unescape("plain"))
+	assert.Equal(t, "a/b c", Unescape("a%2Fb%20c"))
+	assert.Equal(t, "%zz", Unescape("%zz"))
+}
+
+func TestUnescapeSegments(t *testing.T) {
+	assert.Equal(t, "a/b", UnescapeSegments("a/b"))
+	assert.Equal(t, "a/b/c?", UnescapeSegments("a%2Fb/c%3F"))
+	assert.Equal(t, "%zz/a b", UnescapeSegments("%zz/a%20b"))
+}
@@ -0,0 +1,100 @@
+package routem
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// mountParam names the catch-all which holds the rest of the path
+// for a mounted http.Handler.
+const mountParam = "routemMount"
+
+type (
+	// mountedRouter places the Routes of a Router under a prefix
+	// as though they had been created by a Group, scoping them with
+	// the configuration of the RouteCreator they were mounted by.
+	mountedRouter struct {
+		Router
+		prefix string
+		outer  config
+	}
+)
+
+// Compile time type assertion
+var _ Group = &mountedRouter{}
+
+func (m *mountedRouter) Path() string {
+	return m.prefix
+}
+
+func (m *mountedRouter) Host() string {
+	return m.outer.host
+}
+
+// scope applies the configuration of the RouteCreator the Router was
+// mounted by to one of its flattened Routes. The middlewares of the
+// RouteCreator run first and its error handler and timeout are used
+// by Routes which have no error handler or the DefaultTimeout.
+func (m *mountedRouter) scope(r *route) {
+	middlewares := make([]MiddlewareFunc, 0, len(m.outer.middlewares)+len(r.middlewares))
+	middlewares = append(middlewares, m.outer.middlewares...)
+	r.middlewares = append(middlewares, r.middlewares...)
+
+	if r.errorHandler == nil {
+		r.errorHandler = m.outer.errorHandler
+	}
+
+	if r.timeout == DefaultTimeout {
+		r.timeout = m.outer.timeout
+	}
+}
+
+// mountPath returns the path of the Route for a handler mounted at
+// the prefix, which may end with a slash.
+func mountPath(prefix string) string {
+	return strings.TrimSuffix(prefix, "/") + "/*" + mountParam
+}
+
+// wrapMountedHandler serves a mounted handler with a copy of the
+// request whose path is the part of the path below the prefix.
+func wrapMountedHandler(handler http.Handler) HandlerFunc {
+	return func(c context.Context) HTTPError {
+		request := RequestFromContext(c)
+		response := ResponseWriterFromContext(c)
+
+		handler.ServeHTTP(response, stripPrefix(request.WithContext(c), ParamsFromContext(c)[mountParam]))
+
+		return nil
+	}
+}
+
+// stripPrefix copies the request with a path made from the rest of
+// the path below the prefix, keeping the escaping of the original
+// path where it can be found.
+func stripPrefix(request *http.Request, rest string) *http.Request {
+	stripped := new(http.Request)
+	*stripped = *request
+	stripped.URL = new(url.URL)
+	*stripped.URL = *request.URL
+
+	stripped.URL.Path = "/" + rest
+	stripped.URL.RawPath = ""
+
+	segments := strings.Split(request.URL.EscapedPath(), "/")
+	for i := len(segments) - 1; i > 0; i-- {
+		raw := "/" + strings.Join(segments[i:], "/")
+		if path, err := url.PathUnescape(raw); err == nil && path == stripped.URL.Path {
+			// As with url.URL, RawPath is only kept when it differs
+			// from the default encoding
+			if raw != stripped.URL.EscapedPath() {
+				stripped.URL.RawPath = raw
+			}
+			break
+		}
+	}
+
+	return stripped
+}
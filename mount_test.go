@@ -0,0 +1,219 @@
+package routem
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"golang.org/x/net/context"
+)
+
+func TestMountPath(t *testing.T) {
+	assert.Equal(t, "/admin/*"+mountParam, mountPath("/admin"))
+	assert.Equal(t, "/admin/*"+mountParam, mountPath("/admin/"))
+	assert.Equal(t, "/*"+mountParam, mountPath("/"))
+}
+
+func TestMount(t *testing.T) {
+	group := newGroup(testConfig(), testPathTwo)
+	route := group.Mount("/admin/", http.NotFoundHandler())
+
+	assertTestConfig(t, route)
+	assert.Equal(t, AnyMethod, route.Methods())
+	assert.Equal(t, "/admin/*"+mountParam, route.Path())
+	assert.Equal(t, []Routable{route}, group.Routes())
+}
+
+func TestStripPrefix(t *testing.T) {
+	cases := []struct {
+		url     string
+		rest    string
+		path    string
+		rawPath string
+	}{
+		{"/admin/users/42", "users/42", "/users/42", ""},
+		{"/admin/", "", "/", ""},
+		{"/admin/a%2Fb/c%20d", "a/b/c d", "/a/b/c d", "/a%2Fb/c%20d"},
+		{"/tenants/a%2Fb/files/x", "x", "/x", ""},
+	}
+
+	for _, c := range cases {
+		u, err := url.Parse(c.url)
+		require.Nil(t, err)
+		request := &http.Request{Method: "GET", URL: u}
+
+		stripped := stripPrefix(request, c.rest)
+
+		assert.Equal(t, c.path, stripped.URL.Path, c.url)
+		assert.Equal(t, c.rawPath, stripped.URL.RawPath, c.url)
+		assert.Equal(t, c.url, request.URL.RequestURI(), "Original changed")
+	}
+}
+
+func TestWrapMountedHandler(t *testing.T) {
+	handler := wrapMountedHandler(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		fmt.Fprint(response, request.Method, " ", request.URL.Path)
+	}))
+
+	request, err := http.NewRequest("POST", "http://localhost/admin/users/42", nil)
+	require.Nil(t, err)
+	response := httptest.NewRecorder()
+
+	ctx, cancel := NewRequestContext(context.Background(), time.Second, request, response, Params{mountParam: "users/42"})
+	defer cancel()
+
+	assert.Nil(t, handler(ctx))
+	assert.Equal(t, "POST /users/42", response.Body.String())
+}
+
+func TestMountRouter(t *testing.T) {
+	admin := NewRouter(nil)
+	admin.WithTimeout(time.Minute)
+	admin.Get("/users", testHandler).WithName("users")
+	admin.Get("/", testHandler)
+
+	router := NewRouter(&pathHandlerFactory{})
+	router.Get("/", testHandler)
+	mounted := router.MountRouter("/admin/", admin)
+
+	assert.Equal(t, "/admin", mounted.Path())
+	assert.Equal(t, "", mounted.Host())
+
+	// Routes added later are still merged
+	admin.Put("/users/:id", testHandler)
+
+	table, err := router.RouteTable()
+	require.Nil(t, err)
+	require.Len(t, table, 4)
+	assert.Equal(t, "/admin/users", table[1].Path)
+	assert.Equal(t, time.Minute, table[1].Timeout)
+	assert.Equal(t, "/admin/", table[2].Path)
+	assert.Equal(t, "/admin/users/:id", table[3].Path)
+
+	path, err := router.URL("users", nil)
+	require.Nil(t, err)
+	assert.Equal(t, "/admin/users", path)
+
+	handler, err := router.Handler()
+	require.Nil(t, err)
+	assertSwapBody(t, handler, "/,/admin/users,/admin/,/admin/users/:id")
+
+	assert.True(t, router.Remove(mounted))
+	table, err = router.RouteTable()
+	require.Nil(t, err)
+	assert.Len(t, table, 1)
+}
+
+func TestMountRouterInHost(t *testing.T) {
+	admin := NewRouter(nil)
+	admin.Get("/secret", testHandler)
+	admin.WithGroup("/users").Get("/", testHandler)
+
+	router := NewRouter(&pathHandlerFactory{})
+	mounted := router.WithHost("admin.example.com").MountRouter("/admin", admin)
+
+	assert.Equal(t, "admin.example.com", mounted.Host())
+
+	table, err := router.RouteTable()
+	require.Nil(t, err)
+	require.Len(t, table, 2)
+	assert.Equal(t, "admin.example.com", table[0].Host)
+	assert.Equal(t, "/admin/secret", table[0].Path)
+	assert.Equal(t, "admin.example.com", table[1].Host)
+	assert.Equal(t, "/admin/users/", table[1].Path)
+
+	// The Routes of the mounted Router are left alone
+	table, err = admin.RouteTable()
+	require.Nil(t, err)
+	assert.Equal(t, "", table[0].Host)
+}
+
+func TestMountRouterHostConflict(t *testing.T) {
+	admin := NewRouter(nil)
+	admin.WithHost("admin.example.com").Get("/secret", testHandler)
+
+	router := NewRouter(&pathHandlerFactory{})
+	router.WithHost("admin.example.com").MountRouter("/same", admin)
+
+	_, err := router.Handler()
+	assert.Nil(t, err, "Rejected the same host")
+
+	router.WithHost("www.example.com").MountRouter("/other", admin)
+
+	_, err = router.Handler()
+	assert.NotNil(t, err, "Mounted a Router for another host")
+
+	_, err = router.RouteTable()
+	assert.NotNil(t, err)
+}
+
+func TestMountRouterInGroup(t *testing.T) {
+	var order []string
+	middleware := func(name string) MiddlewareFunc {
+		return func(next HandlerFunc) HandlerFunc {
+			return func(ctx context.Context) HTTPError {
+				order = append(order, name)
+				return next(ctx)
+			}
+		}
+	}
+
+	var handled string
+	errorHandler := func(name string) ErrorHandlerFunc {
+		return func(err HTTPError, ctx context.Context) error {
+			handled = name
+			return nil
+		}
+	}
+
+	tools := NewRouter(nil)
+	tools.WithMiddleware(middleware("tools"))
+	tools.Get("/run", func(ctx context.Context) HTTPError {
+		order = append(order, "handler")
+		return nil
+	})
+	tools.Get("/own", testHandler).WithErrorHandler(errorHandler("own")).WithTimeout(time.Second)
+
+	router := NewRouter(&pathHandlerFactory{})
+	admin := router.WithGroup("/admin")
+	admin.WithMiddleware(middleware("admin"))
+	admin.WithErrorHandler(errorHandler("admin"))
+	admin.WithTimeout(5 * time.Second)
+	admin.MountRouter("/tools", tools)
+
+	routes, err := flatten("", "", router.Routes())
+	require.Nil(t, err)
+	require.Len(t, routes, 2)
+
+	run := routes[0]
+	assert.Equal(t, "/admin/tools/run", run.Path())
+	assert.Equal(t, 5*time.Second, run.Timeout())
+
+	handler := run.Handler()
+	middlewares := run.Middlewares()
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	assert.Nil(t, handler(context.Background()))
+	assert.Equal(t, []string{"admin", "tools", "handler"}, order)
+
+	run.ErrorHandler()(nil, context.Background())
+	assert.Equal(t, "admin", handled)
+
+	// A Route keeps its own error handler and timeout
+	own := routes[1]
+	assert.Equal(t, time.Second, own.Timeout())
+	own.ErrorHandler()(nil, context.Background())
+	assert.Equal(t, "own", handled)
+
+	// The Routes of the mounted Router are left alone
+	assert.Len(t, tools.Routes()[0].(Route).Middlewares(), 1)
+	assert.Nil(t, tools.Routes()[0].(Route).ErrorHandler())
+}
@@ -0,0 +1,128 @@
+package routem
+
+import (
+	"crypto/tls"
+	"log"
+	"net"
+	"net/http"
+	"time"
+)
+
+// The defaults used for ServerOptions left at zero. They bound how
+// long a client may hold a connection without making progress so
+// slow clients can not exhaust the server.
+const (
+	DefaultReadHeaderTimeout time.Duration = 10 * time.Second  // Headers should arrive promptly
+	DefaultReadTimeout       time.Duration = 30 * time.Second  // Includes the request body
+	DefaultWriteTimeout      time.Duration = 60 * time.Second  // Longer than any sensible route Timeout
+	DefaultIdleTimeout       time.Duration = 120 * time.Second // Between keep-alive requests
+	DefaultMaxHeaderBytes    int           = 1 << 20           // The same as net/http
+)
+
+type (
+	// ServerOptions configures the http.Server behind a Service.
+	// The zero value gives the defaults used by Run() and RunTLS().
+	//
+	// Timeouts left at zero use the matching Default constant and a
+	// negative timeout disables it. The WriteTimeout covers the whole
+	// time spent serving a request, so it must be longer than the
+	// Timeout of any Route and should be disabled for Services which
+	// stream responses.
+	ServerOptions struct {
+		ReadHeaderTimeout time.Duration
+		ReadTimeout       time.Duration
+		WriteTimeout      time.Duration
+		IdleTimeout       time.Duration
+
+		// MaxHeaderBytes limits the size of request headers,
+		// defaulting to DefaultMaxHeaderBytes.
+		MaxHeaderBytes int
+
+		// ConnState is called as connections change state, as with
+		// http.Server.ConnState.
+		ConnState func(net.Conn, http.ConnState)
+
+		// ErrorLog receives errors accepting connections and
+		// unexpected behavior from handlers. The log package's
+		// standard logger is used when it is nil.
+		ErrorLog *log.Logger
+
+		// ReloadInterval is how often TLS Services check the
+		// certificate and key files for changes. When either has
+		// changed the key pair is loaded and used for new
+		// connections, leaving existing connections alone. Files are
+		// not checked when it is zero.
+		ReloadInterval time.Duration
+
+		// OnReloadError is called with any error reloading the
+		// certificate and key files, in which case the Service keeps
+		// serving the last certificate loaded.
+		OnReloadError func(error)
+
+		// GetCertificate provides the certificate for each TLS
+		// handshake, as with tls.Config.GetCertificate, instead of
+		// the certificate and key files, which are then ignored.
+		GetCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error)
+
+		// HTTP1Only stops TLS Services from negotiating HTTP/2,
+		// which they otherwise offer to clients supporting it.
+		HTTP1Only bool
+
+		// H2C allows clients of Services started without TLS to use
+		// HTTP/2 over cleartext, as is common behind a proxy which
+		// terminates TLS. Clients must use HTTP/2 with prior
+		// knowledge as the Upgrade header is not supported.
+		H2C bool
+	}
+)
+
+// server builds the http.Server for a Service.
+func (o ServerOptions) server(address string, handler http.Handler) *http.Server {
+	maxHeaderBytes := o.MaxHeaderBytes
+	if maxHeaderBytes <= 0 {
+		maxHeaderBytes = DefaultMaxHeaderBytes
+	}
+
+	return &http.Server{
+		Addr:              address,
+		Handler:           handler,
+		ReadHeaderTimeout: timeout(o.ReadHeaderTimeout, DefaultReadHeaderTimeout),
+		ReadTimeout:       timeout(o.ReadTimeout, DefaultReadTimeout),
+		WriteTimeout:      timeout(o.WriteTimeout, DefaultWriteTimeout),
+		IdleTimeout:       timeout(o.IdleTimeout, DefaultIdleTimeout),
+		MaxHeaderBytes:    maxHeaderBytes,
+		ConnState:         o.ConnState,
+		ErrorLog:          o.ErrorLog,
+	}
+}
+
+// timeout returns the default for a zero timeout.
+func timeout(value, def time.Duration) time.Duration {
+	if value == 0 {
+		return def
+	}
+	return value
+}
+
+// protocols returns the protocols a Service should serve.
+func (o ServerOptions) protocols(tls bool) *http.Protocols {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+
+	if tls {
+		protocols.SetHTTP2(!o.HTTP1Only)
+	} else {
+		protocols.SetUnencryptedHTTP2(o.H2C)
+	}
+
+	return protocols
+}
+
+// nextProtos returns the protocols offered by TLS Services using
+// ALPN.
+func (o ServerOptions) nextProtos() []string {
+	if o.HTTP1Only {
+		return []string{"http/1.1"}
+	}
+	return []string{"h2", "http/1.1"}
+}
@@ -0,0 +1,206 @@
+package routem
+
+import (
+	"bytes"
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"golang.org/x/net/context"
+)
+
+const protocolAddress = "localhost:9005"
+
+// protocolHandler reports the protocol a request was served over.
+func protocolHandler() http.Handler {
+	return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		response.Write([]byte(request.Proto))
+	})
+}
+
+// getProto makes a request using the transport and returns the major
+// protocol version of the response.
+func getProto(t *testing.T, transport *http.Transport, url string) int {
+	defer transport.CloseIdleConnections()
+
+	client := &http.Client{Transport: transport}
+
+	response, err := client.Get(url)
+	require.Nil(t, err)
+	response.Body.Close()
+
+	return response.ProtoMajor
+}
+
+func tlsTransport() *http.Transport {
+	return &http.Transport{
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		ForceAttemptHTTP2: true,
+	}
+}
+
+func TestServeTLSNegotiatesHTTP2(t *testing.T) {
+	srv, err := ServeTLS(protocolAddress, testCert, testKey, protocolHandler())
+	require.Nil(t, err)
+	defer srv.Stop()
+
+	assert.Equal(t, 2, getProto(t, tlsTransport(), "https://"+protocolAddress+"/"))
+}
+
+func TestServeTLSHTTP1Only(t *testing.T) {
+	srv, err := ServeTLSWith(protocolAddress, testCert, testKey, protocolHandler(),
+		ServerOptions{HTTP1Only: true})
+	require.Nil(t, err)
+	defer srv.Stop()
+
+	assert.Equal(t, 1, getProto(t, tlsTransport(), "https://"+protocolAddress+"/"))
+}
+
+func TestServeH2C(t *testing.T) {
+	transport := &http.Transport{Protocols: new(http.Protocols)}
+	transport.Protocols.SetUnencryptedHTTP2(true)
+
+	srv, err := Serve(protocolAddress, protocolHandler())
+	require.Nil(t, err)
+
+	_, err = (&http.Client{Transport: transport}).Get("http://" + protocolAddress + "/")
+	assert.NotNil(t, err, "Served h2c without the option")
+	transport.CloseIdleConnections()
+	srv.Stop()
+	srv.Wait()
+
+	srv, err = ServeWith(protocolAddress, protocolHandler(), ServerOptions{H2C: true})
+	require.Nil(t, err)
+	defer srv.Stop()
+
+	assert.Equal(t, 2, getProto(t, transport, "http://"+protocolAddress+"/"))
+}
+
+func TestRunWithOptions(t *testing.T) {
+	router := NewRouter(&testHandlerFactory{})
+
+	srv, err := router.RunTLSWith(protocolAddress, testCert, testKey, ServerOptions{HTTP1Only: true})
+	require.Nil(t, err)
+	assert.Nil(t, srv.Stop())
+	srv.Wait()
+
+	srv, err = router.RunWith(protocolAddress, ServerOptions{H2C: true})
+	require.Nil(t, err)
+	assert.Nil(t, srv.Stop())
+	srv.Wait()
+}
+
+func TestRunWithOptionsFactoryError(t *testing.T) {
+	router := NewRouter(&testHandlerFactory{error: true})
+
+	srv, err := router.RunWith(protocolAddress, ServerOptions{})
+	assert.NotNil(t, err, "RunWith didn't return an error")
+	assert.Nil(t, srv, "RunWith returned a service")
+
+	srv, err = router.RunTLSWith(protocolAddress, testCert, testKey, ServerOptions{})
+	assert.NotNil(t, err, "RunTLSWith didn't return an error")
+	assert.Nil(t, srv, "RunTLSWith returned a service")
+}
+
+func TestServerOptionsDefaults(t *testing.T) {
+	server := ServerOptions{}.server(protocolAddress, http.NotFoundHandler())
+
+	assert.Equal(t, DefaultReadHeaderTimeout, server.ReadHeaderTimeout)
+	assert.Equal(t, DefaultReadTimeout, server.ReadTimeout)
+	assert.Equal(t, DefaultWriteTimeout, server.WriteTimeout)
+	assert.Equal(t, DefaultIdleTimeout, server.IdleTimeout)
+	assert.Equal(t, DefaultMaxHeaderBytes, server.MaxHeaderBytes)
+	assert.Nil(t, server.ConnState)
+	assert.Nil(t, server.ErrorLog)
+}
+
+func TestServerOptionsOverrides(t *testing.T) {
+	logger := log.New(ioutil.Discard, "", 0)
+
+	server := ServerOptions{
+		ReadHeaderTimeout: time.Second,
+		ReadTimeout:       2 * time.Second,
+		WriteTimeout:      -1,
+		IdleTimeout:       3 * time.Second,
+		MaxHeaderBytes:    4096,
+		ConnState:         func(net.Conn, http.ConnState) {},
+		ErrorLog:          logger,
+	}.server(protocolAddress, http.NotFoundHandler())
+
+	assert.Equal(t, time.Second, server.ReadHeaderTimeout)
+	assert.Equal(t, 2*time.Second, server.ReadTimeout)
+	assert.True(t, server.WriteTimeout < 0, "WriteTimeout not disabled")
+	assert.Equal(t, 3*time.Second, server.IdleTimeout)
+	assert.Equal(t, 4096, server.MaxHeaderBytes)
+	assert.NotNil(t, server.ConnState)
+	assert.Equal(t, logger, server.ErrorLog)
+}
+
+func TestServeWithSlowHeaders(t *testing.T) {
+	srv, err := ServeWith(protocolAddress, protocolHandler(),
+		ServerOptions{ReadHeaderTimeout: 50 * time.Millisecond})
+	require.Nil(t, err)
+	defer srv.Stop()
+
+	conn, err := net.Dial("tcp", protocolAddress)
+	require.Nil(t, err)
+	defer conn.Close()
+
+	// Start a request but never finish the headers
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\n"))
+	require.Nil(t, err)
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = conn.Read(make([]byte, 1))
+
+	// The server gives up on the request and hangs up
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestServeWithConnStateAndErrorLog(t *testing.T) {
+	states := make(chan http.ConnState, 10)
+	var logged bytes.Buffer
+	var mu sync.Mutex
+
+	srv, err := ServeTLSWith(protocolAddress, testCert, testKey, protocolHandler(),
+		ServerOptions{
+			ConnState: func(conn net.Conn, state http.ConnState) {
+				states <- state
+			},
+			ErrorLog: log.New(writerFunc(func(b []byte) (int, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				return logged.Write(b)
+			}), "", 0),
+		})
+	require.Nil(t, err)
+
+	// A plain HTTP request fails the TLS handshake
+	response, err := http.Get("http://" + protocolAddress + "/")
+	if err == nil {
+		response.Body.Close()
+	}
+
+	assert.Equal(t, http.StateNew, <-states)
+	assert.Nil(t, srv.Shutdown(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Contains(t, logged.String(), "TLS handshake error")
+}
+
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(b []byte) (int, error) {
+	return f(b)
+}
@@ -0,0 +1,170 @@
+// Package radix provides a routem HandlerFactory backed by a radix
+// tree with compressed static edges.
+//
+// It is built for speed: looking up a route and capturing its
+// parameters does not allocate once the pools used for parameter
+// storage are warm. Serving a request still allocates the context for
+// the route and the goroutine which enforces its timeout. The Params
+// handed to a handler come from a pool and must not be retained after
+// the handler returns.
+//
+// Routes are matched like the trie HandlerFactory: static text takes
+// precedence over ":param" segments, which take precedence over a
+// trailing "*catchall", with backtracking. Paths are matched one
+// escaped segment at a time and parameter values are decoded
+// individually. A path which exists without the requested method is
+// answered with a 405 and an Allow header.
+//
+// Host patterns and parameter constraints are not supported and
+// routes using them are reported as errors by Handler().
+package radix
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/nick-codes/routem"
+	"github.com/nick-codes/routem/internal/dispatch"
+	"github.com/nick-codes/routem/internal/escape"
+
+	"golang.org/x/net/context"
+)
+
+// Compile time type assertions
+var _ http.Handler = &rootNode{}
+var _ routem.HandlerFactory = &factory{}
+
+type factory struct {
+	ctx          context.Context
+	errorHandler routem.ErrorHandlerFunc
+}
+
+type rootNode struct {
+	node
+	dispatcher dispatch.Dispatcher
+}
+
+// NewHandlerFactory constructs a new handler factory which uses a
+// radix tree to look up routes.
+//
+// All routes will be passed a context derived from the context passed
+// to the factory. If no context is passed then context.Background()
+// is used as the root context.
+//
+// If an ErrorHandlerFunc is provided and the route does not have a
+// route specific error handler that handler will be called if a route
+// returns an error. Otherwise a 500 error will be returned to the
+// client.
+func NewHandlerFactory(ctx context.Context, errorHandler routem.ErrorHandlerFunc) routem.HandlerFactory {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &factory{
+		ctx:          ctx,
+		errorHandler: errorHandler,
+	}
+}
+
+func (f *factory) Handler(routes []routem.Route) (http.Handler, error) {
+
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("Received no routes")
+	}
+
+	root := &rootNode{
+		dispatcher: dispatch.Dispatcher{
+			Context:      f.ctx,
+			ErrorHandler: f.errorHandler,
+		},
+	}
+
+	for _, route := range routes {
+		if err := dispatch.CheckRoute(route); err != nil {
+			return nil, err
+		}
+
+		if err := dispatch.CheckNoHost(route); err != nil {
+			return nil, err
+		}
+
+		path := route.Path()
+
+		tokens, names, err := tokenize(path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		n := root.insert(tokens)
+
+		for _, method := range route.Methods() {
+			if existing := n.leaves[method]; existing != nil {
+				return nil, fmt.Errorf("Duplicate route: %s - %s", path, existing.route.Path())
+			}
+		}
+
+		l := &leaf{
+			route:    route,
+			handler:  dispatch.Handler(route),
+			names:    names,
+			catchAll: strings.HasPrefix(path[strings.LastIndex(path, "/")+1:], "*"),
+		}
+
+		if n.leaves == nil {
+			n.leaves = make(map[routem.Method]*leaf)
+		}
+		for _, method := range route.Methods() {
+			n.leaves[method] = l
+		}
+	}
+
+	return root, nil
+}
+
+func (root *rootNode) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	method := routem.Method(request.Method)
+	path := escape.Path(request.URL)
+
+	c := capturePool.Get().(*captures)
+	l := root.lookup(path, method, &c.values)
+
+	if l == nil {
+		c.values = c.values[:0]
+		capturePool.Put(c)
+		root.notFound(response, request, path)
+		return
+	}
+
+	params := l.params(c.values)
+	c.values = c.values[:0]
+	capturePool.Put(c)
+
+	err, committed := root.dispatcher.Run(l.route, l.handler, request, response, params)
+
+	if err != nil && !committed {
+		root.dispatcher.Fail(response, request, l.route, err, params)
+	}
+
+	// A timed out handler may still be using the params
+	if err != dispatch.RequestTimeout {
+		releaseParams(params)
+	}
+}
+
+// notFound reports a 405 with an Allow header when the path exists
+// for other methods and a 404 otherwise.
+func (root *rootNode) notFound(response http.ResponseWriter, request *http.Request, path string) {
+	allowed := make(map[routem.Method]*leaf)
+	root.allowed(path, allowed)
+
+	if len(allowed) == 0 {
+		root.dispatcher.Fail(response, request, nil, dispatch.RouteNotFound, nil)
+		return
+	}
+
+	allow, first := dispatch.AllowHeader(allowed)
+	response.Header().Set("Allow", allow)
+
+	root.dispatcher.Fail(response, request, first.route, dispatch.MethodNotAllowed, nil)
+}
@@ -0,0 +1,34 @@
+package radix
+
+import (
+	"github.com/nick-codes/routem"
+	"github.com/nick-codes/routem/routemtest"
+
+	"testing"
+)
+
+func TestConformance(t *testing.T) {
+	routemtest.Run(t, NewHandlerFactory)
+}
+
+func TestPrecedence(t *testing.T) {
+	routemtest.RunPrecedence(t, NewHandlerFactory)
+}
+
+func TestErrorWithCatchAllDuplicateParamName(t *testing.T) {
+	router := routem.NewRouter(NewHandlerFactory(nil, nil))
+	router.Get("/:name/*name", routemtest.Echo("invalid"))
+	routemtest.AssertInvalid(t, router)
+}
+
+func TestErrorWithHost(t *testing.T) {
+	router := routem.NewRouter(NewHandlerFactory(nil, nil))
+	router.WithHost("example.com").Get("/test", routemtest.Echo("host"))
+	routemtest.AssertInvalid(t, router)
+}
+
+func TestErrorWithConstraint(t *testing.T) {
+	router := routem.NewRouter(NewHandlerFactory(nil, nil))
+	router.Get("/users/:id<int>", routemtest.Echo("constrained"))
+	routemtest.AssertInvalid(t, router)
+}
@@ -0,0 +1,55 @@
+package radix
+
+import (
+	"sync"
+
+	"github.com/nick-codes/routem"
+	"github.com/nick-codes/routem/internal/escape"
+)
+
+// captures holds the raw parameter values found during a lookup.
+type captures struct {
+	values []string
+}
+
+var capturePool = sync.Pool{
+	New: func() interface{} {
+		return &captures{values: make([]string, 0, 8)}
+	},
+}
+
+var paramsPool = sync.Pool{
+	New: func() interface{} {
+		return make(routem.Params, 8)
+	},
+}
+
+// params builds the Params for a leaf from the captured values.
+func (l *leaf) params(values []string) routem.Params {
+	if len(l.names) == 0 {
+		return nil
+	}
+
+	params := paramsPool.Get().(routem.Params)
+	for i, name := range l.names {
+		if l.catchAll && i == len(l.names)-1 {
+			params[name] = escape.UnescapeSegments(values[i])
+		} else {
+			params[name] = escape.Unescape(values[i])
+		}
+	}
+
+	return params
+}
+
+// releaseParams returns Params to the pool once no handler can still
+// be using them.
+func releaseParams(params routem.Params) {
+	if params == nil {
+		return
+	}
+	for name := range params {
+		delete(params, name)
+	}
+	paramsPool.Put(params)
+}
@@ -0,0 +1,35 @@
+package radix
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/nick-codes/routem"
+	"github.com/nick-codes/routem/routemtest"
+
+	"golang.org/x/net/context"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParamsReleased(t *testing.T) {
+	router := routem.NewRouter(NewHandlerFactory(nil, nil))
+	router.Get("/users/:id", func(ctx context.Context) routem.HTTPError {
+		fmt.Fprint(routem.ResponseWriterFromContext(ctx), len(routem.ParamsFromContext(ctx)))
+		return nil
+	})
+	router.Get("/users/:id/posts/:post", routemtest.Echo("post"))
+
+	handler, err := router.Handler()
+	assert.Nil(t, err)
+
+	// Params reused from the pool must not carry stale values
+	response := routemtest.Serve(t, handler, routem.Get, "http://localhost/users/1/posts/2")
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	response = routemtest.Serve(t, handler, routem.Get, "http://localhost/users/1")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "1", response.Body.String())
+}
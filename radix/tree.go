@@ -0,0 +1,257 @@
+package radix
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nick-codes/routem"
+	"github.com/nick-codes/routem/internal/escape"
+)
+
+type nodeKind uint8
+
+const (
+	static nodeKind = iota
+	param
+	catchAll
+)
+
+// A token is a run of static text, which may span several segments,
+// or a single parameter or catch-all from the path of a route.
+type token struct {
+	kind nodeKind
+	text string // escaped static text or the parameter name
+}
+
+// A leaf holds a route for a single method.
+type leaf struct {
+	route    routem.Route
+	handler  routem.HandlerFunc
+	names    []string // parameter names in the order they are captured
+	catchAll bool     // whether the last name is a catch-all
+}
+
+// node is a node in a radix tree with compressed static edges.
+// Static children are indexed by the first byte of their prefix,
+// which is unique among siblings.
+type node struct {
+	kind     nodeKind
+	prefix   string // the static edge leading to this node
+	indices  string
+	children []*node
+	param    *node
+	catchAll *node
+	leaves   map[routem.Method]*leaf
+}
+
+// tokenize splits the path of a route into tokens, escaping static
+// segments the same way request paths are canonicalized.
+func tokenize(path string) ([]token, []string, error) {
+	var tokens []token
+	var names []string
+	seen := make(map[string]struct{})
+
+	parts := strings.Split(path, "/")
+	text := ""
+
+	for i, part := range parts {
+		if i > 0 {
+			text += "/"
+		}
+
+		if !strings.HasPrefix(part, ":") && !strings.HasPrefix(part, "*") {
+			text += escape.Segment(part)
+			continue
+		}
+
+		name := part[1:]
+
+		if len(name) == 0 {
+			return nil, nil, fmt.Errorf("Found an un-named parameter: %s", path)
+		}
+
+		if strings.ContainsAny(name, "<>") {
+			return nil, nil, fmt.Errorf("Parameter constraints are not supported: %s", path)
+		}
+
+		if _, exists := seen[name]; exists {
+			return nil, nil, fmt.Errorf("Route has duplicate parameter: %s", part)
+		}
+		seen[name] = struct{}{}
+
+		kind := param
+		if strings.HasPrefix(part, "*") {
+			if i != len(parts)-1 {
+				return nil, nil, fmt.Errorf("Catch-all must be the last segment: %s", path)
+			}
+			kind = catchAll
+		}
+
+		tokens = append(tokens, token{kind: static, text: text}, token{kind: kind, text: name})
+		names = append(names, name)
+		text = ""
+	}
+
+	if len(text) > 0 {
+		tokens = append(tokens, token{kind: static, text: text})
+	}
+
+	return tokens, names, nil
+}
+
+// insert walks the tokens down from n, creating nodes as needed, and
+// returns the node at the end of the path.
+func (n *node) insert(tokens []token) *node {
+	current := n
+	for _, t := range tokens {
+		switch t.kind {
+		case static:
+			current = current.insertStatic(t.text)
+		case param:
+			if current.param == nil {
+				current.param = &node{kind: param}
+			}
+			current = current.param
+		case catchAll:
+			if current.catchAll == nil {
+				current.catchAll = &node{kind: catchAll}
+			}
+			current = current.catchAll
+		}
+	}
+	return current
+}
+
+// insertStatic adds the static text below n, splitting any edge it
+// shares only part of a prefix with.
+func (n *node) insertStatic(text string) *node {
+	if len(text) == 0 {
+		return n
+	}
+
+	for i := 0; i < len(n.indices); i++ {
+		if n.indices[i] != text[0] {
+			continue
+		}
+
+		child := n.children[i]
+		common := commonPrefix(child.prefix, text)
+
+		if common < len(child.prefix) {
+			split := &node{
+				kind:     static,
+				prefix:   child.prefix[:common],
+				indices:  child.prefix[common : common+1],
+				children: []*node{child},
+			}
+			child.prefix = child.prefix[common:]
+			n.children[i] = split
+			child = split
+		}
+
+		return child.insertStatic(text[common:])
+	}
+
+	child := &node{kind: static, prefix: text}
+	n.indices += text[:1]
+	n.children = append(n.children, child)
+
+	return child
+}
+
+func commonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// lookup finds the leaf for the rest of the path below n, which has
+// already consumed its own edge. Static children are tried before
+// the parameter child and then the catch-all, backtracking when a
+// branch fails. Captured parameter values are appended to values.
+func (n *node) lookup(path string, method routem.Method, values *[]string) *leaf {
+	if len(path) == 0 {
+		if l := n.leaves[method]; l != nil {
+			return l
+		}
+	} else {
+		c := path[0]
+		for i := 0; i < len(n.indices); i++ {
+			if n.indices[i] == c {
+				child := n.children[i]
+				if strings.HasPrefix(path, child.prefix) {
+					if l := child.lookup(path[len(child.prefix):], method, values); l != nil {
+						return l
+					}
+				}
+				break
+			}
+		}
+
+		if n.param != nil {
+			end := strings.IndexByte(path, '/')
+			if end < 0 {
+				end = len(path)
+			}
+			if end > 0 {
+				*values = append(*values, path[:end])
+				if l := n.param.lookup(path[end:], method, values); l != nil {
+					return l
+				}
+				*values = (*values)[:len(*values)-1]
+			}
+		}
+	}
+
+	if n.catchAll != nil {
+		if l := n.catchAll.leaves[method]; l != nil {
+			*values = append(*values, path)
+			return l
+		}
+	}
+
+	return nil
+}
+
+// allowed collects the leaves for every method registered on any
+// node matching the rest of the path below n.
+func (n *node) allowed(path string, allowed map[routem.Method]*leaf) {
+	add := func(leaves map[routem.Method]*leaf) {
+		for method, l := range leaves {
+			if allowed[method] == nil {
+				allowed[method] = l
+			}
+		}
+	}
+
+	if len(path) == 0 {
+		add(n.leaves)
+	} else {
+		c := path[0]
+		for i := 0; i < len(n.indices); i++ {
+			if n.indices[i] == c {
+				child := n.children[i]
+				if strings.HasPrefix(path, child.prefix) {
+					child.allowed(path[len(child.prefix):], allowed)
+				}
+				break
+			}
+		}
+
+		if n.param != nil {
+			end := strings.IndexByte(path, '/')
+			if end < 0 {
+				end = len(path)
+			}
+			if end > 0 {
+				n.param.allowed(path[end:], allowed)
+			}
+		}
+	}
+
+	if n.catchAll != nil {
+		add(n.catchAll.leaves)
+	}
+}
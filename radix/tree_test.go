@@ -0,0 +1,155 @@
+package radix
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nick-codes/routem"
+	"github.com/nick-codes/routem/trie"
+
+	"golang.org/x/net/context"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var raceEnabled = false
+
+var benchmarkRoutes = []string{
+	"/",
+	"/users",
+	"/users/me",
+	"/users/:id",
+	"/users/:id/posts",
+	"/users/:id/posts/:post",
+	"/users/:id/settings",
+	"/teams",
+	"/teams/:team/members/:member",
+	"/static/*filepath",
+	"/api/v1/status",
+	"/api/v1/search",
+}
+
+func benchmarkHandler(t testing.TB, factory routem.HandlerFactory, timeout time.Duration) http.Handler {
+	router := routem.NewRouter(factory)
+	router.WithTimeout(timeout)
+	for _, path := range benchmarkRoutes {
+		router.Get(path, func(ctx context.Context) routem.HTTPError {
+			return nil
+		})
+	}
+
+	handler, err := router.Handler()
+	require.Nil(t, err)
+
+	return handler
+}
+
+func TestTokenize(t *testing.T) {
+	tokens, names, err := tokenize("/users/:id/posts/*rest")
+	require.Nil(t, err)
+	assert.Equal(t, []token{
+		{static, "/users/"},
+		{param, "id"},
+		{static, "/posts/"},
+		{catchAll, "rest"},
+	}, tokens)
+	assert.Equal(t, []string{"id", "rest"}, names)
+
+	tokens, _, err = tokenize("/hello world/")
+	require.Nil(t, err)
+	assert.Equal(t, []token{{static, "/hello%20world/"}}, tokens)
+}
+
+func TestSplitEdges(t *testing.T) {
+	root := &node{}
+	users := root.insertStatic("/users")
+	team := root.insertStatic("/team")
+	teams := root.insertStatic("/teams")
+
+	require.Len(t, root.children, 1)
+	assert.Equal(t, "/", root.children[0].prefix)
+	assert.Equal(t, "users", users.prefix)
+	assert.Equal(t, "team", team.prefix)
+	assert.Equal(t, "s", teams.prefix)
+	assert.Equal(t, team, root.insertStatic("/team"))
+}
+
+func TestLookupDoesNotAllocate(t *testing.T) {
+	if raceEnabled {
+		t.Skip("Allocations are not stable under the race detector")
+	}
+
+	root := benchmarkHandler(t, NewHandlerFactory(nil, nil), routem.DefaultTimeout).(*rootNode)
+
+	for _, path := range []string{"/users/me", "/users/42/posts/7", "/static/css/main.css"} {
+		allocs := testing.AllocsPerRun(100, func() {
+			c := capturePool.Get().(*captures)
+			l := root.lookup(path, routem.Get, &c.values)
+			releaseParams(l.params(c.values))
+			c.values = c.values[:0]
+			capturePool.Put(c)
+		})
+		assert.Equal(t, 0.0, allocs, path)
+	}
+}
+
+func benchmarkServeHTTP(b *testing.B, factory routem.HandlerFactory, timeout time.Duration) {
+	handler := benchmarkHandler(b, factory, timeout)
+	request, err := http.NewRequest("GET", "http://localhost/teams/core/members/42", nil)
+	require.Nil(b, err)
+	response := httptest.NewRecorder()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		handler.ServeHTTP(response, request)
+	}
+}
+
+func benchmarkLookup(b *testing.B, path string) {
+	root := benchmarkHandler(b, NewHandlerFactory(nil, nil), routem.DefaultTimeout).(*rootNode)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c := capturePool.Get().(*captures)
+		l := root.lookup(path, routem.Get, &c.values)
+		releaseParams(l.params(c.values))
+		c.values = c.values[:0]
+		capturePool.Put(c)
+	}
+}
+
+func BenchmarkLookupStatic(b *testing.B) {
+	benchmarkLookup(b, "/api/v1/status")
+}
+
+func BenchmarkLookupParams(b *testing.B) {
+	benchmarkLookup(b, "/teams/core/members/42")
+}
+
+func BenchmarkLookupCatchAll(b *testing.B) {
+	benchmarkLookup(b, "/static/css/site/main.css")
+}
+
+// The ServeHTTP benchmarks compare both backends on the same routes,
+// with and without a timeout for the routes.
+
+func BenchmarkServeHTTPRadix(b *testing.B) {
+	benchmarkServeHTTP(b, NewHandlerFactory(nil, nil), routem.DefaultTimeout)
+}
+
+func BenchmarkServeHTTPRadixNoTimeout(b *testing.B) {
+	benchmarkServeHTTP(b, NewHandlerFactory(nil, nil), routem.NoTimeout)
+}
+
+func BenchmarkServeHTTPTrie(b *testing.B) {
+	benchmarkServeHTTP(b, trie.NewHandlerFactory(nil, nil), routem.DefaultTimeout)
+}
+
+func BenchmarkServeHTTPTrieNoTimeout(b *testing.B) {
+	benchmarkServeHTTP(b, trie.NewHandlerFactory(nil, nil), routem.NoTimeout)
+}
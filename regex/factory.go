@@ -0,0 +1,179 @@
+// Package regex provides a routem HandlerFactory which compiles the
+// path of each Route into a regular expression.
+//
+// Along with whole segment ":param" parameters and a trailing
+// "*catchall", paths may contain "{name}" parameters anywhere within
+// a segment, as in "/files/{name}.{ext}" or "/v{version}/status". A
+// parameter may restrict what it matches with a regular expression,
+// as in "/v{version:[0-9]+}/status". Expressions match the escaped
+// form of the path and the value of a parameter never contains a
+// slash, so "{name:.+}" does not match across segments. A "{name}"
+// without an expression matches one or more characters other than a
+// slash.
+//
+// Routes are tried in the order they were created and the first
+// Route whose path matches and which accepts the method of the
+// request is used. A path which matches only Routes for other methods
+// is answered with a 405 and an Allow header.
+//
+// Timeouts, panics and errors are handled the same way as by the
+// trie HandlerFactory. Host patterns are not supported and routes
+// using them are reported as errors by Handler().
+package regex
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/nick-codes/routem"
+	"github.com/nick-codes/routem/internal/dispatch"
+	"github.com/nick-codes/routem/internal/escape"
+
+	"golang.org/x/net/context"
+)
+
+// Compile time type assertions
+var _ http.Handler = &matcher{}
+var _ routem.HandlerFactory = &factory{}
+
+type (
+	factory struct {
+		ctx          context.Context
+		errorHandler routem.ErrorHandlerFunc
+	}
+
+	entry struct {
+		route   routem.Route
+		handler routem.HandlerFunc
+		pattern *pattern
+		methods map[routem.Method]struct{}
+	}
+
+	matcher struct {
+		entries    []*entry
+		dispatcher dispatch.Dispatcher
+	}
+)
+
+// NewHandlerFactory constructs a new handler factory which matches
+// routes using regular expressions.
+//
+// All routes will be passed a context derived from the context passed
+// to the factory. If no context is passed then context.Background()
+// is used as the root context.
+//
+// If an ErrorHandlerFunc is provided and the route does not have a
+// route specific error handler that handler will be called if a route
+// returns an error. Otherwise a 500 error will be returned to the
+// client.
+func NewHandlerFactory(ctx context.Context, errorHandler routem.ErrorHandlerFunc) routem.HandlerFactory {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &factory{
+		ctx:          ctx,
+		errorHandler: errorHandler,
+	}
+}
+
+func (f *factory) Handler(routes []routem.Route) (http.Handler, error) {
+
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("Received no routes")
+	}
+
+	m := &matcher{
+		dispatcher: dispatch.Dispatcher{
+			Context:      f.ctx,
+			ErrorHandler: f.errorHandler,
+		},
+	}
+
+	for _, route := range routes {
+		if err := dispatch.CheckRoute(route); err != nil {
+			return nil, err
+		}
+
+		if err := dispatch.CheckNoHost(route); err != nil {
+			return nil, err
+		}
+
+		path := route.Path()
+
+		p, err := compile(path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		e := &entry{
+			route:   route,
+			handler: dispatch.Handler(route),
+			pattern: p,
+			methods: make(map[routem.Method]struct{}),
+		}
+
+		for _, method := range route.Methods() {
+			e.methods[method] = struct{}{}
+		}
+
+		// Routes which differ only in parameter names can never be
+		// reached for the methods they share
+		for _, existing := range m.entries {
+			if existing.pattern.regexp.String() != p.regexp.String() {
+				continue
+			}
+			for method := range e.methods {
+				if _, exists := existing.methods[method]; exists {
+					return nil, fmt.Errorf("Duplicate route: %s - %s", path, existing.route.Path())
+				}
+			}
+		}
+
+		m.entries = append(m.entries, e)
+	}
+
+	return m, nil
+}
+
+func (m *matcher) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	method := routem.Method(request.Method)
+	path := escape.Path(request.URL)
+
+	// The first route for each other method handles a 405
+	allowed := make(map[routem.Method]*entry)
+
+	for _, e := range m.entries {
+		params, matched := e.pattern.match(path)
+
+		if !matched {
+			continue
+		}
+
+		if _, exists := e.methods[method]; !exists {
+			for other := range e.methods {
+				if allowed[other] == nil {
+					allowed[other] = e
+				}
+			}
+			continue
+		}
+
+		err, committed := m.dispatcher.Run(e.route, e.handler, request, response, params)
+
+		if err != nil && !committed {
+			m.dispatcher.Fail(response, request, e.route, err, params)
+		}
+		return
+	}
+
+	if len(allowed) == 0 {
+		m.dispatcher.Fail(response, request, nil, dispatch.RouteNotFound, nil)
+		return
+	}
+
+	allow, first := dispatch.AllowHeader(allowed)
+	response.Header().Set("Allow", allow)
+
+	m.dispatcher.Fail(response, request, first.route, dispatch.MethodNotAllowed, nil)
+}
@@ -0,0 +1,142 @@
+package regex
+
+import (
+	"net/http"
+
+	"github.com/nick-codes/routem"
+	"github.com/nick-codes/routem/routemtest"
+
+	"golang.org/x/net/context"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConformance(t *testing.T) {
+	routemtest.Run(t, NewHandlerFactory)
+}
+
+func TestIntraSegmentParams(t *testing.T) {
+	router := routem.NewRouter(NewHandlerFactory(nil, nil))
+	router.Get("/files/{name}.{ext}", routemtest.Echo("file"))
+	router.Get("/v{version}/status", routemtest.Echo("status"))
+
+	cases := []struct {
+		url  string
+		code int
+		body string
+	}{
+		{"/files/report.pdf", http.StatusOK, "file ext=pdf name=report"},
+		{"/files/archive.tar.gz", http.StatusOK, "file ext=gz name=archive.tar"},
+		{"/files/my%20report.pdf", http.StatusOK, "file ext=pdf name=my report"},
+		{"/files/report", http.StatusNotFound, ""},
+		{"/v2/status", http.StatusOK, "status version=2"},
+		{"/v/status", http.StatusNotFound, ""},
+	}
+
+	for _, c := range cases {
+		response := routemtest.ServeRouter(t, router, routem.Get, "http://localhost"+c.url)
+		assert.Equal(t, c.code, response.Code, c.url)
+		if c.code == http.StatusOK {
+			assert.Equal(t, c.body, response.Body.String(), c.url)
+		}
+	}
+}
+
+func TestParamExpressions(t *testing.T) {
+	router := routem.NewRouter(NewHandlerFactory(nil, nil))
+	router.Get("/v{version:[0-9]+}/users/{id:[0-9]{1,4}}", routemtest.Echo("user"))
+	router.Get("/v{version:(beta|rc)[0-9]?}/users/:name", routemtest.Echo("name"))
+
+	cases := []struct {
+		url  string
+		code int
+		body string
+	}{
+		{"/v2/users/42", http.StatusOK, "user id=42 version=2"},
+		{"/v2/users/12345", http.StatusNotFound, ""},
+		{"/vbeta/users/bob", http.StatusOK, "name name=bob version=beta"},
+		{"/vrc1/users/bob", http.StatusOK, "name name=bob version=rc1"},
+		{"/vx/users/bob", http.StatusNotFound, ""},
+	}
+
+	for _, c := range cases {
+		response := routemtest.ServeRouter(t, router, routem.Get, "http://localhost"+c.url)
+		assert.Equal(t, c.code, response.Code, c.url)
+		if c.code == http.StatusOK {
+			assert.Equal(t, c.body, response.Body.String(), c.url)
+		}
+	}
+}
+
+func TestParamExpressionWithinSegment(t *testing.T) {
+	router := routem.NewRouter(NewHandlerFactory(nil, nil))
+	router.Get("/files/{name:.+}.txt", routemtest.Echo("file"))
+	router.Get("/files/*rest", routemtest.Echo("rest"))
+
+	response := routemtest.ServeRouter(t, router, routem.Get, "http://localhost/files/a/b/c.txt")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "rest rest=a/b/c.txt", response.Body.String())
+}
+
+func TestFirstMatchWins(t *testing.T) {
+	router := routem.NewRouter(NewHandlerFactory(nil, nil))
+	router.Get("/users/:id", routemtest.Echo("id"))
+	router.Get("/users/me", routemtest.Echo("me"))
+	router.Put("/users/me", routemtest.Echo("put"))
+
+	response := routemtest.ServeRouter(t, router, routem.Get, "http://localhost/users/me")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "id id=me", response.Body.String())
+
+	// Routes for other methods are skipped
+	response = routemtest.ServeRouter(t, router, routem.Put, "http://localhost/users/me")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "put", response.Body.String())
+}
+
+func TestMethodNotAllowedFirstRoute(t *testing.T) {
+	var handled string
+	errorHandler := func(name string) routem.ErrorHandlerFunc {
+		return func(err routem.HTTPError, ctx context.Context) error {
+			handled = name
+			http.Error(routem.ResponseWriterFromContext(ctx), name, err.Code())
+			return nil
+		}
+	}
+
+	router := routem.NewRouter(NewHandlerFactory(nil, nil))
+	router.Put("/users/{id}", routemtest.Echo("put")).WithErrorHandler(errorHandler("put"))
+	router.Delete("/users/:id", routemtest.Echo("delete")).WithErrorHandler(errorHandler("delete"))
+
+	response := routemtest.ServeRouter(t, router, routem.Get, "http://localhost/users/42")
+	assert.Equal(t, http.StatusMethodNotAllowed, response.Code)
+	assert.Equal(t, "delete", handled)
+}
+
+func TestErrorWithHost(t *testing.T) {
+	router := routem.NewRouter(NewHandlerFactory(nil, nil))
+	router.WithHost("example.com").Get("/test", routemtest.Echo("test"))
+
+	routemtest.AssertInvalid(t, router)
+}
+
+func TestErrorWithInvalidPatterns(t *testing.T) {
+	for _, path := range []string{
+		"/files/{name",
+		"/files/name}",
+		"/files/{}",
+		"/files/{name:[}",
+		"/files/{name}.{name}",
+		"/files/{name:(}",
+		"/users/:id<int>",
+	} {
+		routemtest.AssertInvalidPaths(t, NewHandlerFactory, path)
+	}
+}
+
+func TestErrorWithDuplicatePatterns(t *testing.T) {
+	routemtest.AssertInvalidPaths(t, NewHandlerFactory, "/files/{name}", "/files/:id")
+	routemtest.AssertInvalidPaths(t, NewHandlerFactory, "/v{version}/a", "/v{major}/a")
+}
@@ -0,0 +1,166 @@
+package regex
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/nick-codes/routem/internal/escape"
+)
+
+// A pattern is the path of a route compiled into a regular
+// expression which matches the escaped path of a request.
+type pattern struct {
+	regexp *regexp.Regexp
+	names  []string
+	groups []int  // the submatch index for each name
+	spans  []bool // whether the value may span several segments
+}
+
+// compile turns the path of a route into a pattern. A path may
+// contain whole segment ":name" parameters, a trailing "*name"
+// catch-all and "{name}" or "{name:regexp}" parameters anywhere
+// within a segment.
+func compile(path string) (*pattern, error) {
+	p := &pattern{}
+	source := "^"
+	groups := 0
+	seen := make(map[string]struct{})
+
+	add := func(name string, spans bool, sub int) error {
+		if len(name) == 0 {
+			return fmt.Errorf("Found an un-named parameter: %s", path)
+		}
+		if _, exists := seen[name]; exists {
+			return fmt.Errorf("Route has duplicate parameter: %s", name)
+		}
+		seen[name] = struct{}{}
+
+		groups++
+		p.names = append(p.names, name)
+		p.groups = append(p.groups, groups)
+		p.spans = append(p.spans, spans)
+		groups += sub
+		return nil
+	}
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if i > 0 {
+			source += "/"
+		}
+
+		switch {
+		case strings.HasPrefix(segment, ":"):
+			name := segment[1:]
+			if strings.ContainsAny(name, "<>{}") {
+				return nil, fmt.Errorf("Invalid parameter name: %s", path)
+			}
+			if err := add(name, false, 0); err != nil {
+				return nil, err
+			}
+			source += "([^/]+)"
+
+		case strings.HasPrefix(segment, "*"):
+			if i != len(segments)-1 {
+				return nil, fmt.Errorf("Catch-all must be the last segment: %s", path)
+			}
+			if err := add(segment[1:], true, 0); err != nil {
+				return nil, err
+			}
+			source += "(.*)"
+
+		default:
+			for len(segment) > 0 {
+				open := strings.IndexByte(segment, '{')
+				if open < 0 {
+					open = len(segment)
+				}
+
+				if strings.IndexByte(segment[:open], '}') >= 0 {
+					return nil, fmt.Errorf("Unbalanced braces: %s", path)
+				}
+				source += regexp.QuoteMeta(escape.Segment(segment[:open]))
+
+				if open == len(segment) {
+					break
+				}
+
+				end := closing(segment, open)
+				if end < 0 {
+					return nil, fmt.Errorf("Unbalanced braces: %s", path)
+				}
+
+				name, expr := segment[open+1:end], "[^/]+"
+				if colon := strings.IndexByte(name, ':'); colon >= 0 {
+					name, expr = name[:colon], name[colon+1:]
+				}
+
+				sub, err := regexp.Compile(expr)
+				if err != nil {
+					return nil, fmt.Errorf("Invalid parameter expression in %s: %v", path, err)
+				}
+
+				if err := add(name, false, sub.NumSubexp()); err != nil {
+					return nil, err
+				}
+				source += "(" + expr + ")"
+
+				segment = segment[end+1:]
+			}
+		}
+	}
+
+	re, err := regexp.Compile(source + "$")
+	if err != nil {
+		return nil, fmt.Errorf("Invalid path %s: %v", path, err)
+	}
+	p.regexp = re
+
+	return p, nil
+}
+
+// closing returns the index of the brace closing the one at open,
+// allowing for braces nested in a parameter expression.
+func closing(segment string, open int) int {
+	depth := 0
+	for i := open; i < len(segment); i++ {
+		switch segment[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// match returns the decoded parameters when the pattern matches the
+// escaped path. Only a catch-all may match more than one segment, so
+// an expression such as "{name:.+}" does not match across a slash.
+func (p *pattern) match(path string) (map[string]string, bool) {
+	matches := p.regexp.FindStringSubmatch(path)
+	if matches == nil {
+		return nil, false
+	}
+
+	for i := range p.names {
+		if !p.spans[i] && strings.IndexByte(matches[p.groups[i]], '/') >= 0 {
+			return nil, false
+		}
+	}
+
+	params := make(map[string]string, len(p.names))
+	for i, name := range p.names {
+		if p.spans[i] {
+			params[name] = escape.UnescapeSegments(matches[p.groups[i]])
+		} else {
+			params[name] = escape.Unescape(matches[p.groups[i]])
+		}
+	}
+
+	return params, true
+}
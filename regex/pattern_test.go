@@ -0,0 +1,62 @@
+package regex
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompile(t *testing.T) {
+	cases := []struct {
+		path   string
+		source string
+	}{
+		{"/", "^/$"},
+		{"/users/:id", "^/users/([^/]+)$"},
+		{"/static/*filepath", "^/static/(.*)$"},
+		{"/files/{name}.{ext}", `^/files/([^/]+)\.([^/]+)$`},
+		{"/v{version:[0-9]+}", "^/v([0-9]+)$"},
+		{"/hello world/a+b", `^/hello%20world/a\+b$`},
+	}
+
+	for _, c := range cases {
+		p, err := compile(c.path)
+		require.Nil(t, err, c.path)
+		assert.Equal(t, c.source, p.regexp.String(), c.path)
+	}
+}
+
+func TestCompileNestedGroups(t *testing.T) {
+	p, err := compile("/{kind:(a|b)(c|d)}/{id}")
+	require.Nil(t, err)
+	assert.Equal(t, []int{1, 4}, p.groups)
+
+	params, matched := p.match("/ad/42")
+	assert.True(t, matched)
+	assert.Equal(t, map[string]string{"kind": "ad", "id": "42"}, params)
+}
+
+func TestMatchDecodesParams(t *testing.T) {
+	p, err := compile("/files/:name/*rest")
+	require.Nil(t, err)
+
+	params, matched := p.match("/files/a%2Fb/c%20d/e")
+	assert.True(t, matched)
+	assert.Equal(t, map[string]string{"name": "a/b", "rest": "c d/e"}, params)
+
+	_, matched = p.match("/files/a")
+	assert.False(t, matched)
+}
+
+func TestMatchWithinSegment(t *testing.T) {
+	p, err := compile("/files/{name:.+}.txt")
+	require.Nil(t, err)
+
+	params, matched := p.match("/files/a%2Fb.txt")
+	assert.True(t, matched)
+	assert.Equal(t, map[string]string{"name": "a/b"}, params)
+
+	_, matched = p.match("/files/a/b/c.txt")
+	assert.False(t, matched)
+}
@@ -13,6 +13,7 @@ type (
 		methods []Method
 		path    string
 		handler HandlerFunc
+		name    string
 	}
 )
 
@@ -29,9 +30,21 @@ func (r *route) Path() string {
 func (r *route) Handler() HandlerFunc {
 	return r.handler
 }
+func (r *route) Name() string {
+	return r.name
+}
 func (r *route) Prefix(prefix string) Route {
 	route := newRoute(r.config, r.methods, prefix+r.path, r.handler)
-	return route
+	return route.WithName(r.name)
+}
+
+// =-=-=-=
+// Setters
+// =-=-=-=
+
+func (r *route) WithName(name string) Route {
+	r.name = name
+	return r
 }
 
 // =-=-=-=
@@ -62,7 +75,7 @@ func wrapHTTPHandler(handler http.Handler) HandlerFunc {
 		request := RequestFromContext(c)
 		response := ResponseWriterFromContext(c)
 
-		handler.ServeHTTP(response, request)
+		handler.ServeHTTP(response, request.WithContext(c))
 
 		return nil
 	}
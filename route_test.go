@@ -119,3 +119,36 @@ func TestPrefix(t *testing.T) {
 
 	assertRoute(t, route)
 }
+
+func TestWithName(t *testing.T) {
+	config := defaultConfig()
+	route := newRoute(config, GetMethod, testPath, testHandler)
+
+	assert.Equal(t, "", route.Name())
+
+	route = route.WithName("test")
+	assert.Equal(t, "test", route.Name())
+
+	route = route.Prefix("/")
+	assert.Equal(t, "test", route.Name())
+}
+
+func TestWrapHTTPHandlerContext(t *testing.T) {
+	response, request, _ := setupContextTest(t)
+	ctx, cancel := NewRequestContext(context.Background(), DefaultTimeout, request, response, Params{"id": "42"})
+	defer cancel()
+	ctx = context.WithValue(ctx, adapterKey(0), "value")
+
+	called := false
+	handler := wrapHTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		assert.Equal(t, request.URL, r.URL)
+		assert.Equal(t, Params{"id": "42"}, ParamsFromRequest(r))
+		assert.Equal(t, "value", r.Context().Value(adapterKey(0)))
+		_, hasDeadline := r.Context().Deadline()
+		assert.True(t, hasDeadline)
+	}))
+
+	assert.Nil(t, handler(ctx))
+	assert.True(t, called)
+}
@@ -0,0 +1,228 @@
+package routemtest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/nick-codes/routem"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RunPrecedence runs the precedence tests against the HandlerFactory
+// constructed by newFactory as subtests of t. They are for backends,
+// such as trie and radix, where static text takes precedence over a
+// ":param" segment, which takes precedence over a trailing
+// "*catchall", with backtracking, whatever order the routes were
+// created in.
+func RunPrecedence(t *testing.T, newFactory NewFactory) {
+	tests := []struct {
+		name string
+		test func(*testing.T, NewFactory)
+	}{
+		{"Precedence", TestPrecedence},
+		{"CatchAllPrecedence", TestCatchAllPrecedence},
+		{"MethodBacktracking", TestMethodBacktracking},
+		{"AllowUnion", TestAllowUnion},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			test.test(t, newFactory)
+		})
+	}
+}
+
+// Permutations returns every ordering of the routes, so tests can
+// show that a HandlerFactory does not depend on the order routes were
+// created in.
+func Permutations(routes []routem.Route) [][]routem.Route {
+	if len(routes) <= 1 {
+		return [][]routem.Route{routes}
+	}
+
+	var result [][]routem.Route
+	for i := range routes {
+		rest := make([]routem.Route, 0, len(routes)-1)
+		rest = append(rest, routes[:i]...)
+		rest = append(rest, routes[i+1:]...)
+		for _, perm := range Permutations(rest) {
+			result = append(result, append([]routem.Route{routes[i]}, perm...))
+		}
+	}
+	return result
+}
+
+// namedRoutes returns the Routes of a Router for the paths, each
+// handled by Echo with the name given for it.
+func namedRoutes(names map[string]string) []routem.Route {
+	router := routem.NewRouter(nil)
+
+	routes := make([]routem.Route, 0, len(names))
+	for path, name := range names {
+		routes = append(routes, router.Get(path, Echo(name)))
+	}
+	return routes
+}
+
+// TestPrecedence checks overlapping static, parameter and catch-all
+// routes in every order.
+func TestPrecedence(t *testing.T, newFactory NewFactory) {
+	routes := namedRoutes(map[string]string{
+		"/users/me":          "me",
+		"/users/:id":         "id",
+		"/users/me/settings": "settings",
+		"/users/:id/posts":   "posts",
+		"/users/*rest":       "rest",
+		"/:section/about":    "about",
+	})
+
+	cases := []struct {
+		url  string
+		code int
+		body string
+	}{
+		{"/users/me", http.StatusOK, "me"},
+		{"/users/42", http.StatusOK, "id id=42"},
+		{"/users/me/settings", http.StatusOK, "settings"},
+		{"/users/me/posts", http.StatusOK, "posts id=me"},
+		{"/users/42/posts", http.StatusOK, "posts id=42"},
+		{"/users/42/other", http.StatusOK, "rest rest=42/other"},
+		{"/users/me/settings/more", http.StatusOK, "rest rest=me/settings/more"},
+		{"/users/me/about", http.StatusOK, "rest rest=me/about"},
+		{"/users/about", http.StatusOK, "id id=about"},
+		{"/team/about", http.StatusOK, "about section=team"},
+		{"/team", http.StatusNotFound, ""},
+		{"/team/about/more", http.StatusNotFound, ""},
+	}
+
+	for _, perm := range Permutations(routes) {
+		handler, err := newFactory(nil, nil).Handler(perm)
+		require.Nil(t, err)
+
+		for _, c := range cases {
+			response := Serve(t, handler, routem.Get, "http://localhost"+c.url)
+
+			require.Equal(t, c.code, response.Code, c.url)
+			if c.code == http.StatusOK {
+				require.Equal(t, c.body, response.Body.String(), c.url)
+			}
+		}
+	}
+}
+
+// TestCatchAllPrecedence checks that a catch-all only matches when no
+// static or parameter route does, in every order.
+func TestCatchAllPrecedence(t *testing.T, newFactory NewFactory) {
+	routes := namedRoutes(map[string]string{
+		"/static/*filepath":  "catchall",
+		"/static/robots.txt": "robots",
+		"/static/:file/raw":  "param",
+	})
+
+	cases := []struct {
+		url  string
+		body string
+	}{
+		{"/static/robots.txt", "robots"},
+		{"/static/robots.txt/raw", "param file=robots.txt"},
+		{"/static/main.css", "catchall filepath=main.css"},
+		{"/static/main.css/raw", "param file=main.css"},
+		{"/static/main.css/raw/more", "catchall filepath=main.css/raw/more"},
+		{"/static/", "catchall filepath="},
+	}
+
+	for _, perm := range Permutations(routes) {
+		handler, err := newFactory(nil, nil).Handler(perm)
+		require.Nil(t, err)
+
+		for _, c := range cases {
+			response := Serve(t, handler, routem.Get, "http://localhost"+c.url)
+			assert.Equal(t, http.StatusOK, response.Code, c.url)
+			assert.Equal(t, c.body, response.Body.String(), c.url)
+		}
+	}
+}
+
+// TestMethodBacktracking checks that a static route without the
+// requested method falls back to a parameter route with it.
+func TestMethodBacktracking(t *testing.T, newFactory NewFactory) {
+	router := routem.NewRouter(nil)
+	routes := []routem.Route{
+		router.Get("/users/me", Echo("me")),
+		router.Put("/users/:id", Echo("id")),
+	}
+
+	for _, perm := range Permutations(routes) {
+		handler, err := newFactory(nil, nil).Handler(perm)
+		require.Nil(t, err)
+
+		response := Serve(t, handler, routem.Put, "http://localhost/users/me")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, "id id=me", response.Body.String())
+	}
+}
+
+// TestAllowUnion checks that the Allow header of a 405 lists the
+// methods of every route matching the path.
+func TestAllowUnion(t *testing.T, newFactory NewFactory) {
+	router := routem.NewRouter(newFactory(nil, nil))
+	router.Get("/users/me", Echo("get"))
+	router.Delete("/users/me", Echo("delete"))
+	router.Put("/users/:id", Echo("put"))
+	router.Post("/users/*rest", Echo("post"))
+	router.Patch("/users/:id/posts", Echo("patch"))
+	h := handler(t, router)
+
+	response := Serve(t, h, routem.Options, "http://localhost/users/me")
+	assert.Equal(t, http.StatusMethodNotAllowed, response.Code)
+	assert.Equal(t, "DELETE, GET, POST, PUT", response.Header().Get("Allow"))
+
+	response = Serve(t, h, routem.Get, "http://localhost/users/42")
+	assert.Equal(t, http.StatusMethodNotAllowed, response.Code)
+	assert.Equal(t, "POST, PUT", response.Header().Get("Allow"))
+}
+
+// TestEscapedPaths checks that paths are matched on escaped segments,
+// so an encoded slash stays within its parameter, and that parameter
+// values are decoded.
+func TestEscapedPaths(t *testing.T, newFactory NewFactory) {
+	router := routem.NewRouter(newFactory(nil, nil))
+	router.Get("/files/:name/raw", Echo("file"))
+	router.Get("/static/*rest", Echo("rest"))
+	router.Get("/hello world", Echo("space"))
+	h := handler(t, router)
+
+	cases := []struct {
+		url  string
+		body string
+	}{
+		{"/files/a%2Fb%20c/raw", "file name=a/b c"},
+		{"/static/a%2Fb/c%3F", "rest rest=a/b/c?"},
+		{"/hello%20world", "space"},
+	}
+
+	for _, c := range cases {
+		response := Serve(t, h, routem.Get, "http://localhost"+c.url)
+		assert.Equal(t, http.StatusOK, response.Code, c.url)
+		assert.Equal(t, c.body, response.Body.String(), c.url)
+	}
+
+	// An invalid RawPath is ignored in favour of the decoded Path
+	router = routem.NewRouter(newFactory(nil, nil))
+	router.Get("/files/:name", Echo("file"))
+
+	response := httptest.NewRecorder()
+	handler(t, router).ServeHTTP(response, &http.Request{
+		Method: "GET",
+		URL:    &url.URL{Path: "/files/%zz", RawPath: "/files/%zz"},
+	})
+
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "file name=%zz", response.Body.String())
+}
@@ -0,0 +1,608 @@
+// Package routemtest provides a conformance suite which any
+// routem.HandlerFactory can run to show that it behaves the same way
+// as the HandlerFactory implementations in this repository.
+//
+// A backend runs the suite from its own tests:
+//
+//	func TestConformance(t *testing.T) {
+//		routemtest.Run(t, NewHandlerFactory)
+//	}
+//
+// The suite covers path parameters, duplicate and invalid routes,
+// method handling, timeouts, error handler precedence, root context
+// propagation, middleware order, panic recovery, net/http handlers,
+// mounted handlers, client cancellation and escaped paths.
+//
+// It does not cover the precedence of overlapping routes, which
+// differs between backends. Backends which give static text
+// precedence over parameters, and parameters precedence over a
+// catch-all, whatever order routes were created in can also run
+// RunPrecedence.
+package routemtest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nick-codes/routem"
+
+	"golang.org/x/net/context"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type (
+	// NewFactory constructs the HandlerFactory under test with the
+	// given root context and error handler, either of which may be
+	// nil.
+	NewFactory func(ctx context.Context, errorHandler routem.ErrorHandlerFunc) routem.HandlerFactory
+
+	keyType int
+)
+
+const (
+	rootKey keyType = iota
+	middlewareKey
+	outerKey
+)
+
+// Timeout is the route timeout used by the timeout tests.
+const Timeout = 50 * time.Millisecond
+
+// Run runs every conformance test against the HandlerFactory
+// constructed by newFactory as a subtest of t.
+func Run(t *testing.T, newFactory NewFactory) {
+	tests := []struct {
+		name string
+		test func(*testing.T, NewFactory)
+	}{
+		{"InvalidRoutes", TestInvalidRoutes},
+		{"DuplicateRoutes", TestDuplicateRoutes},
+		{"StaticRouting", TestStaticRouting},
+		{"ParamRouting", TestParamRouting},
+		{"CatchAllRouting", TestCatchAllRouting},
+		{"MethodNotAllowed", TestMethodNotAllowed},
+		{"RequestContext", TestRequestContext},
+		{"RootContext", TestRootContext},
+		{"MiddlewareOrder", TestMiddlewareOrder},
+		{"Timeout", TestTimeout},
+		{"ErrorHandlerPrecedence", TestErrorHandlerPrecedence},
+		{"PanicRecovered", TestPanicRecovered},
+		{"HTTPHandler", TestHTTPHandler},
+		{"ClientCancel", TestClientCancel},
+		{"EscapedPaths", TestEscapedPaths},
+		{"Mount", TestMount},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			test.test(t, newFactory)
+		})
+	}
+}
+
+// =-=-=-=
+// Helpers
+// =-=-=-=
+
+// Echo returns a HandlerFunc which writes the name and the sorted
+// Params it received to the response body.
+func Echo(name string) routem.HandlerFunc {
+	return func(ctx context.Context) routem.HTTPError {
+		params := routem.ParamsFromContext(ctx)
+		keys := make([]string, 0, len(params))
+		for key := range params {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		body := name
+		for _, key := range keys {
+			body += fmt.Sprintf(" %s=%s", key, params[key])
+		}
+
+		fmt.Fprint(routem.ResponseWriterFromContext(ctx), body)
+		return nil
+	}
+}
+
+// Serve sends a request with the given method and URL to handler and
+// returns the recorded response.
+func Serve(t *testing.T, handler http.Handler, method routem.Method, url string) *httptest.ResponseRecorder {
+	request, err := http.NewRequest(string(method), url, nil)
+	require.Nil(t, err)
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+
+	return response
+}
+
+// ServeRouter builds the handler for the router, which must succeed,
+// and sends it a request in the same way as Serve.
+func ServeRouter(t *testing.T, router routem.Router, method routem.Method, url string) *httptest.ResponseRecorder {
+	return Serve(t, handler(t, router), method, url)
+}
+
+// AssertInvalid checks that the handler for the router can not be
+// built.
+func AssertInvalid(t *testing.T, router routem.Router, msgAndArgs ...interface{}) {
+	handler, err := router.Handler()
+	t.Logf("%s", err)
+
+	assert.Nil(t, handler, msgAndArgs...)
+	assert.NotNil(t, err, msgAndArgs...)
+}
+
+// AssertInvalidPaths checks that a router with a GET route for each
+// of the paths can not be built by the HandlerFactory.
+func AssertInvalidPaths(t *testing.T, newFactory NewFactory, paths ...string) {
+	router := routem.NewRouter(newFactory(nil, nil))
+	for _, path := range paths {
+		router.Get(path, Echo(path))
+	}
+
+	AssertInvalid(t, router, paths)
+}
+
+func handler(t *testing.T, router routem.Router) http.Handler {
+	handler, err := router.Handler()
+	require.Nil(t, err)
+	require.NotNil(t, handler)
+	return handler
+}
+
+// =-=-=
+// Tests
+// =-=-=
+
+// TestInvalidRoutes checks that invalid routes are reported as errors.
+func TestInvalidRoutes(t *testing.T, newFactory NewFactory) {
+	for _, routes := range [][]routem.Route{nil, {}, {nil}} {
+		handler, err := newFactory(nil, nil).Handler(routes)
+		assert.Nil(t, handler)
+		assert.NotNil(t, err)
+	}
+
+	for _, path := range []string{
+		"",
+		"no/slash",
+		"/double//slash",
+		"/:",
+		"/:name/:name",
+		"/static/*",
+		"/static/*rest/more",
+	} {
+		AssertInvalidPaths(t, newFactory, path)
+	}
+}
+
+// TestDuplicateRoutes checks that routes for the same method and
+// path are reported as errors, even when parameter names differ.
+func TestDuplicateRoutes(t *testing.T, newFactory NewFactory) {
+	for _, paths := range [][]string{
+		{"/test", "/test"},
+		{"/test/:name", "/test/:name"},
+		{"/test/:name", "/test/:id"},
+		{"/static/*filepath", "/static/*rest"},
+	} {
+		AssertInvalidPaths(t, newFactory, paths...)
+	}
+
+	router := routem.NewRouter(newFactory(nil, nil))
+	router.Get("/test", Echo("get"))
+	router.Put("/test", Echo("put"))
+
+	response := Serve(t, handler(t, router), routem.Put, "http://localhost/test")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "put", response.Body.String())
+}
+
+// TestStaticRouting checks that static paths are routed and unknown
+// paths result in a 404.
+func TestStaticRouting(t *testing.T, newFactory NewFactory) {
+	router := routem.NewRouter(newFactory(nil, nil))
+	router.Get("/", Echo("root"))
+	router.Get("/test", Echo("test"))
+	router.Get("/test/deeper", Echo("deeper"))
+	h := handler(t, router)
+
+	for url, body := range map[string]string{
+		"/":            "root",
+		"/test":        "test",
+		"/test/deeper": "deeper",
+	} {
+		response := Serve(t, h, routem.Get, "http://localhost"+url)
+		assert.Equal(t, http.StatusOK, response.Code, url)
+		assert.Equal(t, body, response.Body.String(), url)
+	}
+
+	for _, url := range []string{"/missing", "/test/missing", "/test/deeper/missing"} {
+		response := Serve(t, h, routem.Get, "http://localhost"+url)
+		assert.Equal(t, http.StatusNotFound, response.Code, url)
+	}
+}
+
+// TestParamRouting checks that parameter values are passed in the
+// Params of the request.
+func TestParamRouting(t *testing.T, newFactory NewFactory) {
+	router := routem.NewRouter(newFactory(nil, nil))
+	router.Get("/users/:id", Echo("user"))
+	router.Get("/users/:id/posts/:post", Echo("post"))
+	h := handler(t, router)
+
+	response := Serve(t, h, routem.Get, "http://localhost/users/42")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "user id=42", response.Body.String())
+
+	response = Serve(t, h, routem.Get, "http://localhost/users/42/posts/hello%20world")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "post id=42 post=hello world", response.Body.String())
+
+	response = Serve(t, h, routem.Get, "http://localhost/users/42/posts")
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
+
+// TestCatchAllRouting checks that a catch-all receives the rest of
+// the path, which may be empty.
+func TestCatchAllRouting(t *testing.T, newFactory NewFactory) {
+	router := routem.NewRouter(newFactory(nil, nil))
+	router.Get("/static/*filepath", Echo("static"))
+	router.Get("/proxy/:host/*rest", Echo("proxy"))
+	h := handler(t, router)
+
+	response := Serve(t, h, routem.Get, "http://localhost/static/css/site/main.css")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "static filepath=css/site/main.css", response.Body.String())
+
+	response = Serve(t, h, routem.Get, "http://localhost/static/")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "static filepath=", response.Body.String())
+
+	response = Serve(t, h, routem.Get, "http://localhost/proxy/example.com/a/b/c")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "proxy host=example.com rest=a/b/c", response.Body.String())
+}
+
+// TestMethodNotAllowed checks that a path which exists for other
+// methods results in a 405 with a sorted Allow header, which is
+// passed to the error handlers like any other error. It avoids GET,
+// which some backends also answer HEAD requests for.
+func TestMethodNotAllowed(t *testing.T, newFactory NewFactory) {
+	router := routem.NewRouter(newFactory(nil, nil))
+	router.Put("/test", Echo("put"))
+	router.Delete("/test", Echo("delete"))
+	h := handler(t, router)
+
+	response := Serve(t, h, routem.Post, "http://localhost/test")
+	assert.Equal(t, http.StatusMethodNotAllowed, response.Code)
+	assert.Equal(t, "DELETE, PUT", response.Header().Get("Allow"))
+
+	var code int
+	router = routem.NewRouter(newFactory(nil, func(err routem.HTTPError, ctx context.Context) error {
+		code = err.Code()
+		http.Error(routem.ResponseWriterFromContext(ctx), "Custom Page", err.Code())
+		return nil
+	}))
+	router.Put("/test", Echo("put"))
+
+	response = Serve(t, handler(t, router), routem.Post, "http://localhost/test")
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+	assert.Equal(t, http.StatusMethodNotAllowed, response.Code)
+	assert.Equal(t, "PUT", response.Header().Get("Allow"))
+	assert.Equal(t, "Custom Page\n", response.Body.String())
+}
+
+// TestRequestContext checks that the request and response are
+// available from the context passed to a handler.
+func TestRequestContext(t *testing.T, newFactory NewFactory) {
+	called := false
+	router := routem.NewRouter(newFactory(nil, nil))
+	router.Get("/test", func(ctx context.Context) routem.HTTPError {
+		called = true
+		assert.Equal(t, "/test", routem.RequestFromContext(ctx).URL.Path)
+		routem.ResponseWriterFromContext(ctx).WriteHeader(http.StatusAccepted)
+		_, hasDeadline := ctx.Deadline()
+		assert.True(t, hasDeadline)
+		return nil
+	})
+
+	response := Serve(t, handler(t, router), routem.Get, "http://localhost/test")
+	assert.True(t, called)
+	assert.Equal(t, http.StatusAccepted, response.Code)
+}
+
+// TestRootContext checks that handlers receive a context derived from
+// the root context passed to the factory.
+func TestRootContext(t *testing.T, newFactory NewFactory) {
+	called := false
+	ctx := context.WithValue(context.Background(), rootKey, "root")
+	router := routem.NewRouter(newFactory(ctx, nil))
+	router.Get("/test", func(ctx context.Context) routem.HTTPError {
+		called = true
+		assert.Equal(t, "root", ctx.Value(rootKey))
+		return nil
+	})
+
+	response := Serve(t, handler(t, router), routem.Get, "http://localhost/test")
+	assert.True(t, called)
+	assert.Equal(t, http.StatusOK, response.Code)
+}
+
+// TestMiddlewareOrder checks that middleware runs in the order it was
+// added, outermost first, and that each one sees the context passed
+// on by the one before it.
+func TestMiddlewareOrder(t *testing.T, newFactory NewFactory) {
+	middleware := func(name string) routem.MiddlewareFunc {
+		return func(next routem.HandlerFunc) routem.HandlerFunc {
+			return func(ctx context.Context) routem.HTTPError {
+				trail, _ := ctx.Value(middlewareKey).(string)
+				return next(context.WithValue(ctx, middlewareKey, trail+name+" "))
+			}
+		}
+	}
+
+	router := routem.NewRouter(newFactory(nil, nil))
+	router.WithMiddleware(middleware("router"))
+	group := router.WithGroup("/group")
+	group.WithMiddleware(middleware("group"))
+	route := group.Get("/test", func(ctx context.Context) routem.HTTPError {
+		fmt.Fprint(routem.ResponseWriterFromContext(ctx), ctx.Value(middlewareKey), "handler")
+		return nil
+	})
+	route.WithMiddlewares([]routem.MiddlewareFunc{middleware("first"), middleware("second")})
+
+	response := Serve(t, handler(t, router), routem.Get, "http://localhost/group/test")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "router group first second handler", response.Body.String())
+}
+
+// TestTimeout checks that a handler which runs past the timeout for
+// its route has its context cancelled and that a 408 is passed to
+// the error handler.
+func TestTimeout(t *testing.T, newFactory NewFactory) {
+	done := make(chan error, 1)
+	var code int
+	router := routem.NewRouter(newFactory(nil, nil))
+	route := router.Get("/test", func(ctx context.Context) routem.HTTPError {
+		<-ctx.Done()
+		done <- ctx.Err()
+		return nil
+	})
+	route.WithTimeout(Timeout)
+	route.WithErrorHandler(func(err routem.HTTPError, ctx context.Context) error {
+		code = err.Code()
+		http.Error(routem.ResponseWriterFromContext(ctx), "Timed Out", err.Code())
+		return nil
+	})
+
+	response := Serve(t, handler(t, router), routem.Get, "http://localhost/test")
+	assert.Equal(t, http.StatusRequestTimeout, code)
+	assert.Equal(t, http.StatusRequestTimeout, response.Code)
+	assert.NotNil(t, <-done)
+
+	// Without an error handler a timeout is an internal error
+	router = routem.NewRouter(newFactory(nil, nil))
+	router.Get("/test", func(ctx context.Context) routem.HTTPError {
+		<-ctx.Done()
+		return nil
+	}).WithTimeout(Timeout)
+
+	response = Serve(t, handler(t, router), routem.Get, "http://localhost/test")
+	assert.Equal(t, http.StatusInternalServerError, response.Code)
+}
+
+// TestErrorHandlerPrecedence checks that the error handler for a
+// route takes precedence over the factory error handler, which takes
+// precedence over the default internal error response.
+func TestErrorHandlerPrecedence(t *testing.T, newFactory NewFactory) {
+	failing := func(ctx context.Context) routem.HTTPError {
+		return routem.NewHTTPError(http.StatusTeapot, fmt.Errorf("Teapot"))
+	}
+	errorHandler := func(name string) routem.ErrorHandlerFunc {
+		return func(err routem.HTTPError, ctx context.Context) error {
+			http.Error(routem.ResponseWriterFromContext(ctx), name, err.Code())
+			return nil
+		}
+	}
+	failingHandler := func(err routem.HTTPError, ctx context.Context) error {
+		return fmt.Errorf("Error handling an error: %v", err)
+	}
+
+	cases := []struct {
+		factory routem.ErrorHandlerFunc
+		route   routem.ErrorHandlerFunc
+		url     string
+		code    int
+		body    string
+	}{
+		{nil, nil, "/test", http.StatusInternalServerError, ""},
+		{errorHandler("factory"), nil, "/test", http.StatusTeapot, "factory\n"},
+		{nil, errorHandler("route"), "/test", http.StatusTeapot, "route\n"},
+		{errorHandler("factory"), errorHandler("route"), "/test", http.StatusTeapot, "route\n"},
+		{nil, failingHandler, "/test", http.StatusInternalServerError, ""},
+		{failingHandler, nil, "/test", http.StatusInternalServerError, ""},
+		{nil, nil, "/missing", http.StatusNotFound, ""},
+		{errorHandler("factory"), errorHandler("route"), "/missing", http.StatusNotFound, "factory\n"},
+		{failingHandler, nil, "/missing", http.StatusInternalServerError, ""},
+	}
+
+	for i, c := range cases {
+		router := routem.NewRouter(newFactory(nil, c.factory))
+		route := router.Get("/test", failing)
+		if c.route != nil {
+			route.WithErrorHandler(c.route)
+		}
+
+		response := Serve(t, handler(t, router), routem.Get, "http://localhost"+c.url)
+		assert.Equal(t, c.code, response.Code, "case %d", i)
+		if len(c.body) > 0 {
+			assert.Equal(t, c.body, response.Body.String(), "case %d", i)
+		}
+	}
+}
+
+// TestPanicRecovered checks that a panic in a handler is recovered
+// and passed to the error handler as a routem.PanicError, without the
+// panic value reaching the client, and that http.ErrAbortHandler
+// still aborts the response.
+func TestPanicRecovered(t *testing.T, newFactory NewFactory) {
+	router := routem.NewRouter(newFactory(nil, nil))
+	router.Get("/test", func(ctx context.Context) routem.HTTPError {
+		panic("boom")
+	})
+
+	response := Serve(t, handler(t, router), routem.Get, "http://localhost/test")
+	assert.Equal(t, http.StatusInternalServerError, response.Code)
+	assert.NotContains(t, response.Body.String(), "boom")
+
+	var panicErr routem.PanicError
+	router = routem.NewRouter(newFactory(nil, nil))
+	router.Get("/test", func(ctx context.Context) routem.HTTPError {
+		panic("boom")
+	}).WithErrorHandler(func(err routem.HTTPError, ctx context.Context) error {
+		panicErr, _ = err.(routem.PanicError)
+		http.Error(routem.ResponseWriterFromContext(ctx), "Oops", err.Code())
+		return nil
+	})
+
+	response = Serve(t, handler(t, router), routem.Get, "http://localhost/test")
+	assert.Equal(t, http.StatusInternalServerError, response.Code)
+	assert.Equal(t, "Oops\n", response.Body.String())
+	require.NotNil(t, panicErr)
+	assert.Equal(t, "boom", panicErr.Value())
+	assert.True(t, strings.Contains(string(panicErr.Stack()), "panic"))
+
+	router = routem.NewRouter(newFactory(nil, nil))
+	router.Get("/abort", func(ctx context.Context) routem.HTTPError {
+		routem.ResponseWriterFromContext(ctx).Write([]byte("partial"))
+		panic(http.ErrAbortHandler)
+	})
+
+	server := httptest.NewServer(handler(t, router))
+	defer server.Close()
+
+	// net/http closes the connection without finishing the response
+	_, err := http.Get(server.URL + "/abort")
+	assert.NotNil(t, err, "Response not aborted")
+}
+
+// TestHTTPHandler checks that an http.Handler registered with
+// WithHTTP() is passed a request carrying the routem context.
+func TestHTTPHandler(t *testing.T, newFactory NewFactory) {
+	router := routem.NewRouter(newFactory(nil, nil))
+	router.WithMiddleware(func(next routem.HandlerFunc) routem.HandlerFunc {
+		return func(ctx context.Context) routem.HTTPError {
+			return next(context.WithValue(ctx, middlewareKey, "middleware"))
+		}
+	})
+	router.GetHTTP("/users/:id", http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		fmt.Fprint(response, routem.ParamsFromRequest(request)["id"], " ", request.Context().Value(middlewareKey))
+	}))
+
+	response := Serve(t, handler(t, router), routem.Get, "http://localhost/users/42")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "42 middleware", response.Body.String())
+}
+
+// TestClientCancel checks that net/http handlers are passed a request
+// whose context keeps the values from the server and outer net/http
+// middleware, and is cancelled when the client goes away.
+func TestClientCancel(t *testing.T, newFactory NewFactory) {
+	type seen struct {
+		root, outer interface{}
+		server      bool
+		err         error
+	}
+
+	started := make(chan struct{}, 1)
+	results := make(chan seen, 1)
+
+	router := routem.NewRouter(newFactory(context.WithValue(context.Background(), rootKey, "root"), nil))
+	router.WithTimeout(time.Minute)
+	router.GetHTTP("/wait", http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		ctx := request.Context()
+		started <- struct{}{}
+		<-ctx.Done()
+		_, server := ctx.Value(http.ServerContextKey).(*http.Server)
+		results <- seen{ctx.Value(rootKey), ctx.Value(outerKey), server, ctx.Err()}
+	}))
+	h := handler(t, router)
+
+	server := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		h.ServeHTTP(response, request.WithContext(context.WithValue(request.Context(), outerKey, "outer")))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	request, err := http.NewRequest("GET", server.URL+"/wait", nil)
+	require.Nil(t, err)
+
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	_, err = http.DefaultClient.Do(request.WithContext(ctx))
+	assert.NotNil(t, err, "Request not cancelled")
+
+	select {
+	case result := <-results:
+		assert.Equal(t, "root", result.root)
+		assert.Equal(t, "outer", result.outer)
+		assert.True(t, result.server, "Missing the server")
+		assert.Equal(t, context.Canceled, result.err)
+	case <-time.After(time.Second):
+		t.Fatal("Handler not cancelled with the client")
+	}
+}
+
+// TestMount checks that a mounted http.Handler receives requests for
+// every method below its prefix with the prefix stripped, and that
+// the Routes of a mounted Router are served under its prefix.
+func TestMount(t *testing.T, newFactory NewFactory) {
+	mounted := http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		fmt.Fprint(response, request.Method, " ", request.URL.Path)
+	})
+
+	admin := routem.NewRouter(nil)
+	admin.Get("/users/:id", Echo("admin"))
+
+	router := routem.NewRouter(newFactory(nil, nil))
+	router.Get("/readme", Echo("readme"))
+	router.Mount("/files/", mounted)
+	router.MountRouter("/admin", admin)
+	h := handler(t, router)
+
+	cases := []struct {
+		method routem.Method
+		url    string
+		code   int
+		body   string
+	}{
+		{routem.Get, "/files/css/main.css", http.StatusOK, "GET /css/main.css"},
+		{routem.Post, "/files/upload", http.StatusOK, "POST /upload"},
+		{routem.Delete, "/files/", http.StatusOK, "DELETE /"},
+		{routem.Get, "/files/a%20b", http.StatusOK, "GET /a b"},
+		{routem.Get, "/readme", http.StatusOK, "readme"},
+		{routem.Get, "/files", http.StatusNotFound, ""},
+		{routem.Get, "/admin/users/42", http.StatusOK, "admin id=42"},
+		{routem.Get, "/admin/other", http.StatusNotFound, ""},
+	}
+
+	for _, c := range cases {
+		response := Serve(t, h, c.method, "http://localhost"+c.url)
+		assert.Equal(t, c.code, response.Code, c.url)
+		if c.code == http.StatusOK {
+			assert.Equal(t, c.body, response.Body.String(), c.url)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package routem
 import (
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 )
 
 type (
@@ -30,72 +32,144 @@ func NewRouter(factory HandlerFactory) Router {
 // =-=-=-=
 
 func (r *router) Run(address string) (Service, error) {
-	handler, err := r.Handler()
-
-	if err != nil {
-		return nil, err
-	}
-
-	s := newService(address, handler)
+	return r.RunWith(address, ServerOptions{})
+}
 
-	err = s.run()
+func (r *router) RunWith(address string, options ServerOptions) (Service, error) {
+	handler, err := r.Handler()
 
 	if err != nil {
 		return nil, err
 	}
 
-	return s, nil
+	return ServeWith(address, handler, options)
 }
 
 func (r *router) RunTLS(address, certFile, keyFile string) (Service, error) {
-	handler, err := r.Handler()
-
-	if err != nil {
-		return nil, err
-	}
-
-	s := newService(address, handler)
+	return r.RunTLSWith(address, certFile, keyFile, ServerOptions{})
+}
 
-	err = s.runTLS(certFile, keyFile)
+func (r *router) RunTLSWith(address, certFile, keyFile string, options ServerOptions) (Service, error) {
+	handler, err := r.Handler()
 
 	if err != nil {
 		return nil, err
 	}
 
-	return s, nil
+	return ServeTLSWith(address, certFile, keyFile, handler, options)
 }
 
 // =-=-=-=
 // Helpers
 // =-=-=-=
 
-func flatten(prefix string, routes []Routable) ([]Route, error) {
+func flatten(prefix, host string, routes []Routable) ([]Route, error) {
 	flat := make([]Route, 0, len(routes))
-	for _, route := range routes {
-		group, isGroup := route.(Group)
+	for _, routable := range routes {
+		routableHost, err := scopeHost(host, routable.Host())
+		if err != nil {
+			return nil, err
+		}
+
+		group, isGroup := routable.(Group)
 		if isGroup {
-			groupRoutes, err := flatten(prefix+group.Path(), group.Routes())
+			groupRoutes, err := flatten(prefix+group.Path(), routableHost, group.Routes())
 			if err != nil {
 				return nil, err
 			}
+			mounted, isMounted := group.(*mountedRouter)
 			for _, gr := range groupRoutes {
+				if isMounted {
+					// flatten() only returns copies made by Prefix()
+					mounted.scope(gr.(*route))
+				}
 				flat = append(flat, gr)
 			}
 		} else {
-			route, isRoute := route.(Route)
+			r, isRoute := routable.(Route)
 			// This is impossible but just in case
 			if !isRoute {
-				return nil, fmt.Errorf("Found Routable not a Group or Route? WTF! %v", route)
+				return nil, fmt.Errorf("Found Routable not a Group or Route? WTF! %v", routable)
 			}
-			flat = append(flat, route.Prefix(prefix))
+			prefixed := r.Prefix(prefix)
+			if prefixed.Host() != routableHost {
+				// Prefix() returns a copy so this leaves r alone
+				prefixed.(*route).host = routableHost
+			}
+			flat = append(flat, prefixed)
 		}
 	}
 
 	return flat, nil
 }
 
+// scopeHost returns the host a Routable is restricted to when it is
+// nested in a Group restricted to the outer host, which is only the
+// case for the Routes of a mounted Router.
+func scopeHost(outer, inner string) (string, error) {
+	if len(inner) == 0 || inner == outer {
+		return outer, nil
+	}
+	if len(outer) == 0 {
+		return inner, nil
+	}
+	return "", fmt.Errorf("Host %s conflicts with the host it is mounted in: %s", inner, outer)
+}
+
+func namedRoutes(routes []Route) (map[string]Route, error) {
+	named := make(map[string]Route)
+	for _, route := range routes {
+		name := route.Name()
+		if len(name) == 0 {
+			continue
+		}
+		if existing, exists := named[name]; exists {
+			return nil, fmt.Errorf("Duplicate route name %s: %s - %s", name, route.Path(), existing.Path())
+		}
+		named[name] = route
+	}
+
+	return named, nil
+}
+
+func expandPath(path string, params Params) (string, error) {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if !strings.HasPrefix(part, ":") && !strings.HasPrefix(part, "*") {
+			continue
+		}
+
+		// Drop any constraint such as ":id<int>"
+		name := part[1:]
+		if i := strings.Index(name, "<"); i >= 0 {
+			name = name[:i]
+		}
+
+		value, exists := params[name]
+		if !exists {
+			return "", fmt.Errorf("Missing parameter %s for path: %s", name, path)
+		}
+
+		if strings.HasPrefix(part, ":") {
+			parts[i] = url.PathEscape(value)
+		} else {
+			values := strings.Split(value, "/")
+			for j, v := range values {
+				values[j] = url.PathEscape(v)
+			}
+			parts[i] = strings.Join(values, "/")
+		}
+	}
+
+	return strings.Join(parts, "/"), nil
+}
+
 func (r *router) Handler() (http.Handler, error) {
-	routes, err := flatten("", r.Routes())
+	routes, err := flatten("", "", r.Routes())
+
+	if err == nil {
+		_, err = namedRoutes(routes)
+	}
 
 	if err == nil {
 		return r.factory.Handler(routes)
@@ -103,3 +177,68 @@ func (r *router) Handler() (http.Handler, error) {
 
 	return nil, err
 }
+
+func (r *router) URL(name string, params Params) (string, error) {
+	routes, err := flatten("", "", r.Routes())
+
+	if err != nil {
+		return "", err
+	}
+
+	named, err := namedRoutes(routes)
+
+	if err != nil {
+		return "", err
+	}
+
+	route, exists := named[name]
+
+	if !exists {
+		return "", fmt.Errorf("No route named: %s", name)
+	}
+
+	return expandPath(route.Path(), params)
+}
+
+func (r *router) Walk(walker func(RouteInfo) error) error {
+	routes, err := flatten("", "", r.Routes())
+
+	if err != nil {
+		return err
+	}
+
+	for _, route := range routes {
+		info := RouteInfo{
+			Name:            route.Name(),
+			Methods:         route.Methods(),
+			Host:            route.Host(),
+			Path:            route.Path(),
+			Timeout:         route.Timeout(),
+			Middlewares:     len(route.Middlewares()),
+			HasErrorHandler: route.ErrorHandler() != nil,
+		}
+
+		err = walker(info)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *router) RouteTable() ([]RouteInfo, error) {
+	var table []RouteInfo
+
+	err := r.Walk(func(info RouteInfo) error {
+		table = append(table, info)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return table, nil
+}
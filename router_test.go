@@ -3,6 +3,7 @@ package routem
 import (
 	"fmt"
 	"net/http"
+	"time"
 
 	_ "net/http/httptest"
 	"testing"
@@ -209,3 +210,138 @@ func TestFlattenWithGroup(t *testing.T) {
 	assert.Equal(t, "/blah", hf.routes[0].Path())
 	assert.Equal(t, "/test", hf.routes[1].Path())
 }
+
+func TestURL(t *testing.T) {
+	router := NewRouter(&testHandlerFactory{})
+
+	router.Get("/home", testHandler).WithName("home")
+
+	group := router.WithGroup("/users")
+	group.Get("/:id/posts/:post", testHandler).WithName("post")
+	group.Get("/:id/files/*path", testHandler).WithName("file")
+
+	url, err := router.URL("home", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "/home", url)
+
+	url, err = router.URL("post", Params{"id": "42", "post": "a b"})
+	assert.Nil(t, err)
+	assert.Equal(t, "/users/42/posts/a%20b", url)
+
+	url, err = router.URL("file", Params{"id": "42", "path": "docs/read me.txt"})
+	assert.Nil(t, err)
+	assert.Equal(t, "/users/42/files/docs/read%20me.txt", url)
+}
+
+func TestURLErrors(t *testing.T) {
+	router := NewRouter(&testHandlerFactory{})
+
+	router.Get("/users/:id", testHandler).WithName("user")
+
+	_, err := router.URL("missing", nil)
+	assert.NotNil(t, err)
+
+	_, err = router.URL("user", Params{"other": "42"})
+	assert.NotNil(t, err)
+}
+
+func TestDuplicateRouteNames(t *testing.T) {
+	hf := &testHandlerFactory{}
+	router := NewRouter(hf)
+
+	router.Get("/one", testHandler).WithName("dup")
+	router.WithGroup("/group").Get("/two", testHandler).WithName("dup")
+
+	_, err := router.Handler()
+	assert.NotNil(t, err)
+	assert.Nil(t, hf.routes)
+
+	_, err = router.URL("dup", nil)
+	assert.NotNil(t, err)
+}
+
+func TestRouteTable(t *testing.T) {
+	router := NewRouter(&testHandlerFactory{})
+
+	router.Get("/home", testHandler).WithName("home")
+
+	group := router.WithGroup("/api")
+	group.WithTimeout(time.Minute)
+	group.WithMiddleware(testMiddleware)
+	group.WithErrorHandler(testErrorHandler)
+	group.Crud("/users/:id", testHandler)
+
+	table, err := router.RouteTable()
+
+	require.Nil(t, err)
+	require.Equal(t, 2, len(table))
+
+	assert.Equal(t, RouteInfo{
+		Name:    "home",
+		Methods: GetMethod,
+		Path:    "/home",
+		Timeout: DefaultTimeout,
+	}, table[0])
+
+	assert.Equal(t, RouteInfo{
+		Methods:         CrudMethod,
+		Path:            "/api/users/:id",
+		Timeout:         time.Minute,
+		Middlewares:     1,
+		HasErrorHandler: true,
+	}, table[1])
+}
+
+func TestWalkStopsOnError(t *testing.T) {
+	router := NewRouter(&testHandlerFactory{})
+
+	router.Get("/one", testHandler)
+	router.Get("/two", testHandler)
+
+	walked := 0
+	err := router.Walk(func(info RouteInfo) error {
+		walked++
+		return fmt.Errorf("stop")
+	})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, walked)
+}
+
+func TestRouteTableFlattenError(t *testing.T) {
+	router := &router{}
+
+	router.routes = append(router.routes, &nonRoute{})
+
+	table, err := router.RouteTable()
+
+	assert.NotNil(t, err)
+	assert.Nil(t, table)
+}
+
+func TestFlattenWithHost(t *testing.T) {
+	hf := &testHandlerFactory{}
+
+	router := NewRouter(hf)
+
+	router.Get("/blah", testHandler)
+	router.WithHost("admin.example.com").WithGroup("/admin").Get("/test", testHandler)
+
+	table, err := router.RouteTable()
+
+	require.Nil(t, err)
+	require.Equal(t, 2, len(table))
+	assert.Equal(t, "", table[0].Host)
+	assert.Equal(t, "admin.example.com", table[1].Host)
+	assert.Equal(t, "/admin/test", table[1].Path)
+}
+
+func TestURLWithConstraint(t *testing.T) {
+	router := NewRouter(&testHandlerFactory{})
+
+	router.Get("/items/:id<int>", testHandler).WithName("item")
+
+	url, err := router.URL("item", Params{"id": "42"})
+	assert.Nil(t, err)
+	assert.Equal(t, "/items/42", url)
+}
@@ -4,19 +4,71 @@ import (
 	"crypto/tls"
 	"net"
 	"net/http"
+	"sync"
+
+	"golang.org/x/net/context"
 )
 
 type (
 	service struct {
 		address  string
+		options  ServerOptions
 		listener net.Listener
 		server   *http.Server
 		err      error
 		started  chan struct{}
 		running  chan struct{}
+
+		// mutex guards stopping and shutdowns, the number of
+		// Shutdown calls still draining connections. drained is
+		// signalled whenever shutdowns falls.
+		mutex     sync.Mutex
+		drained   *sync.Cond
+		stopping  bool
+		shutdowns int
 	}
 )
 
+// Serve serves an http.Handler, such as a SwappableHandler, on the
+// given address in the same way as Runnable.Run().
+func Serve(address string, handler http.Handler) (Service, error) {
+	return ServeWith(address, handler, ServerOptions{})
+}
+
+// ServeWith serves an http.Handler on the given address using the
+// ServerOptions.
+func ServeWith(address string, handler http.Handler, options ServerOptions) (Service, error) {
+	s := newService(address, handler, options)
+
+	err := s.run()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// ServeTLS serves an http.Handler on the given address using the
+// certificate and key files in the same way as Runnable.RunTLS().
+func ServeTLS(address, certFile, keyFile string, handler http.Handler) (Service, error) {
+	return ServeTLSWith(address, certFile, keyFile, handler, ServerOptions{})
+}
+
+// ServeTLSWith serves an http.Handler on the given address using the
+// certificate and key files and the ServerOptions.
+func ServeTLSWith(address, certFile, keyFile string, handler http.Handler, options ServerOptions) (Service, error) {
+	s := newService(address, handler, options)
+
+	err := s.runTLS(certFile, keyFile)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
 func (s *service) Address() string {
 	return s.address
 }
@@ -36,9 +88,36 @@ func (s *service) Wait() error {
 }
 
 func (s *service) Stop() error {
+	s.mutex.Lock()
+	s.stopping = true
+	s.mutex.Unlock()
+
 	return s.listener.Close()
 }
 
+func (s *service) Shutdown(ctx context.Context) error {
+	// Wait() returns once the connections are drained
+	s.mutex.Lock()
+	s.stopping = true
+	s.shutdowns++
+	s.mutex.Unlock()
+
+	err := s.server.Shutdown(ctx)
+
+	if err != nil {
+		s.server.Close()
+	}
+
+	s.mutex.Lock()
+	s.shutdowns--
+	s.drained.Broadcast()
+	s.mutex.Unlock()
+
+	<-s.running
+
+	return err
+}
+
 func (s *service) run() error {
 	listener, err := net.Listen("tcp", s.address)
 
@@ -47,6 +126,7 @@ func (s *service) run() error {
 	}
 
 	s.listener = listener
+	s.server.Protocols = s.options.protocols(false)
 
 	s.serve()
 
@@ -56,14 +136,21 @@ func (s *service) run() error {
 func (s *service) runTLS(certFile, keyFile string) error {
 	config := &tls.Config{}
 
-	config.NextProtos = []string{"http/1.1"}
+	config.NextProtos = s.options.nextProtos()
 
-	var err error
-	config.Certificates = make([]tls.Certificate, 1)
-	config.Certificates[0], err = tls.LoadX509KeyPair(certFile, keyFile)
+	var loader *certificateLoader
 
-	if err != nil {
-		return err
+	if s.options.GetCertificate != nil {
+		config.GetCertificate = s.options.GetCertificate
+	} else {
+		var err error
+		loader, err = newCertificateLoader(certFile, keyFile, s.options.OnReloadError)
+
+		if err != nil {
+			return err
+		}
+
+		config.GetCertificate = loader.GetCertificate
 	}
 
 	listener, err := net.Listen("tcp", s.address)
@@ -72,7 +159,12 @@ func (s *service) runTLS(certFile, keyFile string) error {
 		return err
 	}
 
+	if loader != nil && s.options.ReloadInterval > 0 {
+		go loader.watch(s.options.ReloadInterval, s.running)
+	}
+
 	s.listener = tls.NewListener(listener, config)
+	s.server.Protocols = s.options.protocols(true)
 
 	s.serve()
 
@@ -82,22 +174,32 @@ func (s *service) runTLS(certFile, keyFile string) error {
 func (s *service) serve() {
 	go func() {
 		close(s.started)
-		s.err = s.server.Serve(s.listener)
+		err := s.server.Serve(s.listener)
+
+		s.mutex.Lock()
+		if s.stopping {
+			for s.shutdowns > 0 {
+				s.drained.Wait()
+			}
+			err = ErrServiceStopped
+		}
+		s.mutex.Unlock()
+
+		s.err = err
 		close(s.running)
 	}()
 	<-s.started
 }
 
-func newService(address string, handler http.Handler) *service {
+func newService(address string, handler http.Handler, options ServerOptions) *service {
 	s := &service{
 		address: address,
-		server: &http.Server{
-			Addr:    address,
-			Handler: handler,
-		},
+		options: options,
+		server:  options.server(address, handler),
 		started: make(chan struct{}),
 		running: make(chan struct{}),
 	}
+	s.drained = sync.NewCond(&s.mutex)
 
 	return s
 }
@@ -0,0 +1,110 @@
+package routem
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"golang.org/x/net/context"
+)
+
+const shutdownAddress = "localhost:9002"
+
+// blockingHandler blocks each request until release is closed.
+func blockingHandler(started chan<- struct{}, release <-chan struct{}) http.Handler {
+	return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		started <- struct{}{}
+		<-release
+		fmt.Fprint(response, "done")
+	})
+}
+
+func TestStopWaitReturnsSentinel(t *testing.T) {
+	srv, err := Serve(shutdownAddress, http.NotFoundHandler())
+	require.Nil(t, err)
+
+	assert.Nil(t, srv.Stop())
+	assert.Equal(t, ErrServiceStopped, srv.Wait())
+}
+
+func TestShutdownDrainsRequests(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+
+	srv, err := Serve(shutdownAddress, blockingHandler(started, release))
+	require.Nil(t, err)
+
+	responses := make(chan *http.Response, 1)
+	go func() {
+		response, err := http.Get("http://" + shutdownAddress + "/")
+		assert.Nil(t, err)
+		responses <- response
+	}()
+	<-started
+
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- srv.Shutdown(context.Background())
+	}()
+
+	// The active request holds up the shutdown
+	select {
+	case <-shutdown:
+		t.Fatal("Shutdown returned with an active request")
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.True(t, srv.IsRunning(), "Stopped with an active request")
+
+	close(release)
+
+	assert.Nil(t, <-shutdown)
+	assert.Equal(t, ErrServiceStopped, srv.Wait())
+	assert.False(t, srv.IsRunning())
+
+	response := <-responses
+	require.NotNil(t, response)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	response.Body.Close()
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	defer close(release)
+
+	srv, err := Serve(shutdownAddress, blockingHandler(started, release))
+	require.Nil(t, err)
+
+	failed := make(chan error, 1)
+	go func() {
+		_, err := http.Get("http://" + shutdownAddress + "/")
+		failed <- err
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	assert.Equal(t, context.DeadlineExceeded, srv.Shutdown(ctx))
+	assert.Equal(t, ErrServiceStopped, srv.Wait())
+
+	// The connection is closed on the client
+	assert.NotNil(t, <-failed)
+}
+
+func TestShutdownAfterStop(t *testing.T) {
+	// Shutdown may start while the serve goroutine is finishing
+	for i := 0; i < 20; i++ {
+		srv, err := Serve(shutdownAddress, http.NotFoundHandler())
+		require.Nil(t, err)
+
+		assert.Nil(t, srv.Stop())
+		assert.Nil(t, srv.Shutdown(context.Background()))
+		assert.Equal(t, ErrServiceStopped, srv.Wait())
+	}
+}
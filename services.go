@@ -0,0 +1,169 @@
+package routem
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+type (
+	// A ServiceGroup owns several Services and shuts them down
+	// together. Run() waits until a signal is received, Stop() is
+	// called or any of the Services stops, then gracefully shuts
+	// down every Service and runs the shutdown hooks in the order
+	// they were added.
+	ServiceGroup struct {
+		mu       sync.Mutex
+		services []Service
+		hooks    []func(context.Context) error
+		stop     chan struct{}
+		stopOnce sync.Once
+	}
+
+	// SignalError is the reason a ServiceGroup stopped when it
+	// received a signal.
+	SignalError struct {
+		Signal os.Signal
+	}
+
+	// ServiceGroupError is returned by ServiceGroup.Run() with the
+	// reason the group stopped and any errors from shutting down
+	// the Services or running the hooks.
+	ServiceGroupError struct {
+		// Reason is a *SignalError, ErrServiceStopped after Stop()
+		// or the error from the Service which stopped first.
+		Reason error
+		Errors []error
+	}
+)
+
+// NewServiceGroup constructs a ServiceGroup owning the Services.
+func NewServiceGroup(services ...Service) *ServiceGroup {
+	return &ServiceGroup{
+		services: services,
+		stop:     make(chan struct{}),
+	}
+}
+
+// RunUntilSignal runs the Services in a ServiceGroup until the
+// process receives SIGINT or SIGTERM, allowing the timeout for them
+// to shut down.
+func RunUntilSignal(timeout time.Duration, services ...Service) error {
+	return NewServiceGroup(services...).Run(timeout, os.Interrupt, syscall.SIGTERM)
+}
+
+// Add adds a Service to the group. It must be called before Run().
+func (g *ServiceGroup) Add(service Service) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.services = append(g.services, service)
+}
+
+// OnShutdown adds a hook which is run once every Service has shut
+// down. Hooks are run in the order they were added and are passed a
+// context which expires with the shutdown timeout.
+func (g *ServiceGroup) OnShutdown(hook func(context.Context) error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.hooks = append(g.hooks, hook)
+}
+
+// Stop causes Run() to shut down the group.
+func (g *ServiceGroup) Stop() {
+	g.stopOnce.Do(func() {
+		close(g.stop)
+	})
+}
+
+// Run blocks until one of the signals is received, Stop() is called
+// or any Service stops. It then shuts down every Service, allowing
+// the timeout for them and the hooks to finish, and returns a
+// *ServiceGroupError describing why and how the group stopped.
+func (g *ServiceGroup) Run(timeout time.Duration, signals ...os.Signal) error {
+	g.mu.Lock()
+	services := append([]Service(nil), g.services...)
+	hooks := append([]func(context.Context) error(nil), g.hooks...)
+	g.mu.Unlock()
+
+	received := make(chan os.Signal, 1)
+	if len(signals) > 0 {
+		signal.Notify(received, signals...)
+		defer signal.Stop(received)
+	}
+
+	stopped := make(chan error, len(services))
+	for _, service := range services {
+		go func(service Service) {
+			stopped <- fmt.Errorf("Service on %s stopped: %w", service.Address(), service.Wait())
+		}(service)
+	}
+
+	result := &ServiceGroupError{}
+
+	select {
+	case sig := <-received:
+		result.Reason = &SignalError{Signal: sig}
+	case <-g.stop:
+		result.Reason = ErrServiceStopped
+	case err := <-stopped:
+		result.Reason = err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	errs := make([]error, len(services))
+	var wg sync.WaitGroup
+	for i, service := range services {
+		wg.Add(1)
+		go func(i int, service Service) {
+			defer wg.Done()
+			if err := service.Shutdown(ctx); err != nil {
+				errs[i] = fmt.Errorf("Shutting down service on %s: %w", service.Address(), err)
+			}
+		}(i, service)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			result.Errors = append(result.Errors, err)
+		}
+	}
+
+	for _, hook := range hooks {
+		if err := hook(ctx); err != nil {
+			result.Errors = append(result.Errors, err)
+		}
+	}
+
+	return result
+}
+
+func (e *SignalError) Error() string {
+	return fmt.Sprintf("Received signal: %v", e.Signal)
+}
+
+func (e *ServiceGroupError) Error() string {
+	if len(e.Errors) == 0 {
+		return e.Reason.Error()
+	}
+
+	messages := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		messages = append(messages, err.Error())
+	}
+
+	return fmt.Sprintf("%v (shutdown errors: %s)", e.Reason, strings.Join(messages, "; "))
+}
+
+// Unwrap returns the reason the group stopped.
+func (e *ServiceGroupError) Unwrap() error {
+	return e.Reason
+}
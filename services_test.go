@@ -0,0 +1,138 @@
+package routem
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	groupAddress      = "localhost:9003"
+	groupAddressOther = "localhost:9004"
+)
+
+func serveGroup(t *testing.T) (*ServiceGroup, Service, Service) {
+	first, err := Serve(groupAddress, http.NotFoundHandler())
+	require.Nil(t, err)
+
+	second, err := Serve(groupAddressOther, http.NotFoundHandler())
+	require.Nil(t, err)
+
+	return NewServiceGroup(first, second), first, second
+}
+
+func TestServiceGroupStop(t *testing.T) {
+	group, first, second := serveGroup(t)
+
+	var order []string
+	group.OnShutdown(func(ctx context.Context) error {
+		assert.False(t, first.IsRunning(), "Hook ran before shutdown")
+		assert.False(t, second.IsRunning(), "Hook ran before shutdown")
+		order = append(order, "first")
+		return nil
+	})
+	group.OnShutdown(func(ctx context.Context) error {
+		order = append(order, "second")
+		return fmt.Errorf("hook failed")
+	})
+
+	go group.Stop()
+	err := group.Run(time.Second)
+
+	var groupErr *ServiceGroupError
+	require.True(t, errors.As(err, &groupErr))
+	assert.Equal(t, ErrServiceStopped, groupErr.Reason)
+	assert.True(t, errors.Is(err, ErrServiceStopped))
+	require.Len(t, groupErr.Errors, 1)
+	assert.Contains(t, err.Error(), "hook failed")
+	assert.Equal(t, []string{"first", "second"}, order)
+}
+
+func TestServiceGroupServiceStopped(t *testing.T) {
+	group, first, second := serveGroup(t)
+
+	go first.Stop()
+	err := group.Run(time.Second)
+
+	var groupErr *ServiceGroupError
+	require.True(t, errors.As(err, &groupErr))
+	assert.Contains(t, groupErr.Reason.Error(), groupAddress)
+	assert.True(t, errors.Is(err, ErrServiceStopped))
+	assert.Empty(t, groupErr.Errors)
+	assert.False(t, second.IsRunning())
+}
+
+func TestServiceGroupSignal(t *testing.T) {
+	group, _, second := serveGroup(t)
+
+	process, err := os.FindProcess(os.Getpid())
+	require.Nil(t, err)
+
+	// Stops the signal killing the test process before Run() has
+	// registered for it
+	guard := make(chan os.Signal, 1)
+	signal.Notify(guard, os.Interrupt)
+	defer signal.Stop(guard)
+
+	done := make(chan error)
+	go func() {
+		done <- group.Run(time.Second, os.Interrupt)
+	}()
+
+	// Signals sent before Run() registers only reach the guard, so
+	// keep sending until Run() sees one
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	var result error
+	for result == nil {
+		if err := process.Signal(os.Interrupt); err != nil {
+			group.Stop()
+			<-done
+			t.Skip("Unable to signal the test process")
+		}
+
+		select {
+		case result = <-done:
+		case <-ticker.C:
+		}
+	}
+
+	var signalErr *SignalError
+	require.True(t, errors.As(result, &signalErr))
+	assert.Equal(t, os.Interrupt, signalErr.Signal)
+	assert.False(t, second.IsRunning())
+}
+
+func TestServiceGroupShutdownTimeout(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	defer close(release)
+
+	srv, err := Serve(groupAddress, blockingHandler(started, release))
+	require.Nil(t, err)
+
+	go http.Get("http://" + groupAddress + "/")
+	<-started
+
+	group := NewServiceGroup()
+	group.Add(srv)
+
+	go group.Stop()
+	err = group.Run(50 * time.Millisecond)
+
+	var groupErr *ServiceGroupError
+	require.True(t, errors.As(err, &groupErr))
+	require.Len(t, groupErr.Errors, 1)
+	assert.True(t, errors.Is(groupErr.Errors[0], context.DeadlineExceeded))
+}
@@ -0,0 +1,246 @@
+// Package stdmux provides a routem HandlerFactory which serves Routes
+// with a net/http ServeMux, using the method and wildcard patterns
+// added to it in Go 1.22.
+//
+// Each Route is translated into one ServeMux pattern per method. A
+// ":param" segment becomes "{param}", a trailing "*catchall" becomes
+// "{catchall...}" and a path ending in a slash only matches that
+// exact path. A Route with a static Host() is restricted to that
+// host. The values ServeMux finds for each wildcard are passed to
+// handlers as Params.
+//
+// ServeMux decides which Route serves a request, so the more specific
+// of two overlapping patterns wins and patterns which overlap without
+// either being more specific are reported as errors by Handler(), as
+// are parameter names which are not valid Go identifiers, host
+// patterns and parameter constraints. As with ServeMux, a Route for
+// GET also serves HEAD requests.
+//
+// Requests which do not match a pattern exactly, including those
+// ServeMux would redirect to a cleaned path, are answered with a 404,
+// or a 405 and an Allow header when the path exists for other
+// methods, and passed to the error handlers like any other error.
+package stdmux
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/nick-codes/routem"
+	"github.com/nick-codes/routem/internal/dispatch"
+
+	"golang.org/x/net/context"
+)
+
+// Compile time type assertions
+var _ http.Handler = &mux{}
+var _ routem.HandlerFactory = &factory{}
+
+type (
+	factory struct {
+		ctx          context.Context
+		errorHandler routem.ErrorHandlerFunc
+	}
+
+	entry struct {
+		route      routem.Route
+		handler    routem.HandlerFunc
+		names      []string
+		dispatcher *dispatch.Dispatcher
+	}
+
+	mux struct {
+		serveMux   *http.ServeMux
+		methods    []string
+		dispatcher dispatch.Dispatcher
+	}
+)
+
+// NewHandlerFactory constructs a new handler factory which serves
+// routes with a net/http ServeMux.
+//
+// All routes will be passed a context derived from the context passed
+// to the factory. If no context is passed then context.Background()
+// is used as the root context.
+//
+// If an ErrorHandlerFunc is provided and the route does not have a
+// route specific error handler that handler will be called if a route
+// returns an error. Otherwise a 500 error will be returned to the
+// client.
+func NewHandlerFactory(ctx context.Context, errorHandler routem.ErrorHandlerFunc) routem.HandlerFactory {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &factory{
+		ctx:          ctx,
+		errorHandler: errorHandler,
+	}
+}
+
+func (f *factory) Handler(routes []routem.Route) (http.Handler, error) {
+
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("Received no routes")
+	}
+
+	m := &mux{
+		serveMux: http.NewServeMux(),
+		dispatcher: dispatch.Dispatcher{
+			Context:      f.ctx,
+			ErrorHandler: f.errorHandler,
+		},
+	}
+
+	methods := make(map[string]struct{})
+
+	for _, route := range routes {
+		if err := dispatch.CheckRoute(route); err != nil {
+			return nil, err
+		}
+
+		path, names, err := translate(route.Host(), route.Path())
+
+		if err != nil {
+			return nil, err
+		}
+
+		e := &entry{
+			route:      route,
+			handler:    dispatch.Handler(route),
+			names:      names,
+			dispatcher: &m.dispatcher,
+		}
+
+		for _, method := range route.Methods() {
+			pattern := string(method) + " " + path
+
+			if err := m.handle(pattern, e); err != nil {
+				return nil, fmt.Errorf("Invalid route %s: %v", route.Path(), err)
+			}
+
+			methods[string(method)] = struct{}{}
+		}
+	}
+
+	for method := range methods {
+		m.methods = append(m.methods, method)
+	}
+	sort.Strings(m.methods)
+
+	return m, nil
+}
+
+// translate turns the host and path of a route, which has passed
+// dispatch.CheckRoute, into a ServeMux pattern without a method,
+// returning the names of the wildcards.
+func translate(host, path string) (string, []string, error) {
+	if strings.ContainsAny(host, ":/{}") {
+		return "", nil, fmt.Errorf("Host patterns are not supported: %s", host)
+	}
+
+	var names []string
+	segments := strings.Split(path, "/")
+
+	for i, segment := range segments {
+		switch {
+		case strings.HasPrefix(segment, ":"):
+			if strings.ContainsAny(segment, "<>") {
+				return "", nil, fmt.Errorf("Parameter constraints are not supported: %s", path)
+			}
+			names = append(names, segment[1:])
+			segments[i] = "{" + segment[1:] + "}"
+
+		case strings.HasPrefix(segment, "*"):
+			if i != len(segments)-1 {
+				return "", nil, fmt.Errorf("Catch-all must be the last segment: %s", path)
+			}
+			names = append(names, segment[1:])
+			segments[i] = "{" + segment[1:] + "...}"
+
+		case i == len(segments)-1 && len(segment) == 0:
+			// Without this a trailing slash matches every path below it
+			segments[i] = "{$}"
+		}
+	}
+
+	return host + strings.Join(segments, "/"), names, nil
+}
+
+// handle registers a pattern, reporting the panics of ServeMux for
+// invalid or conflicting patterns as errors.
+func (m *mux) handle(pattern string, e *entry) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("%v", recovered)
+		}
+	}()
+
+	m.serveMux.Handle(pattern, e)
+
+	return nil
+}
+
+func (e *entry) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	var params routem.Params
+	if len(e.names) > 0 {
+		params = make(routem.Params, len(e.names))
+		for _, name := range e.names {
+			params[name] = request.PathValue(name)
+		}
+	}
+
+	err, committed := e.dispatcher.Run(e.route, e.handler, request, response, params)
+
+	if err != nil && !committed {
+		e.dispatcher.Fail(response, request, e.route, err, params)
+	}
+}
+
+// lookup returns the entry ServeMux would use for a request, or nil
+// if it would redirect the request or has no pattern for it.
+func (m *mux) lookup(request *http.Request) *entry {
+	handler, _ := m.serveMux.Handler(request)
+	e, _ := handler.(*entry)
+	return e
+}
+
+func (m *mux) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if m.lookup(request) != nil {
+		m.serveMux.ServeHTTP(response, request)
+		return
+	}
+
+	// The path may still exist for other methods
+	allowed := make(map[routem.Method]*entry)
+
+	for _, method := range m.methods {
+		if method == request.Method {
+			continue
+		}
+
+		other := *request
+		other.Method = method
+
+		if e := m.lookup(&other); e != nil {
+			allowed[routem.Method(method)] = e
+		}
+	}
+
+	if len(allowed) == 0 {
+		m.dispatcher.Fail(response, request, nil, dispatch.RouteNotFound, nil)
+		return
+	}
+
+	// A route for GET also serves HEAD
+	var extra []routem.Method
+	if allowed[routem.Get] != nil {
+		extra = append(extra, routem.Head)
+	}
+
+	allow, first := dispatch.AllowHeader(allowed, extra...)
+	response.Header().Set("Allow", allow)
+
+	m.dispatcher.Fail(response, request, first.route, dispatch.MethodNotAllowed, nil)
+}
@@ -0,0 +1,123 @@
+package stdmux
+
+import (
+	"net/http"
+
+	"github.com/nick-codes/routem"
+	"github.com/nick-codes/routem/routemtest"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConformance(t *testing.T) {
+	routemtest.Run(t, NewHandlerFactory)
+}
+
+func TestTranslate(t *testing.T) {
+	cases := []struct {
+		host    string
+		path    string
+		pattern string
+		names   []string
+	}{
+		{"", "/", "/{$}", nil},
+		{"", "/users/", "/users/{$}", nil},
+		{"", "/users/:id", "/users/{id}", []string{"id"}},
+		{"", "/static/*filepath", "/static/{filepath...}", []string{"filepath"}},
+		{"example.com", "/:a/:b", "example.com/{a}/{b}", []string{"a", "b"}},
+	}
+
+	for _, c := range cases {
+		pattern, names, err := translate(c.host, c.path)
+		require.Nil(t, err, c.path)
+		assert.Equal(t, c.pattern, pattern, c.path)
+		assert.Equal(t, c.names, names, c.path)
+	}
+}
+
+func TestMoreSpecificPatternWins(t *testing.T) {
+	router := routem.NewRouter(NewHandlerFactory(nil, nil))
+	router.Get("/users/:id", routemtest.Echo("id"))
+	router.Get("/users/me", routemtest.Echo("me"))
+
+	response := routemtest.ServeRouter(t, router, routem.Get, "http://localhost/users/me")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "me", response.Body.String())
+
+	response = routemtest.ServeRouter(t, router, routem.Get, "http://localhost/users/42")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "id id=42", response.Body.String())
+}
+
+func TestTrailingSlashExact(t *testing.T) {
+	router := routem.NewRouter(NewHandlerFactory(nil, nil))
+	router.Get("/", routemtest.Echo("root"))
+	router.Get("/users/", routemtest.Echo("users"))
+
+	response := routemtest.ServeRouter(t, router, routem.Get, "http://localhost/users/42")
+	assert.Equal(t, http.StatusNotFound, response.Code)
+
+	response = routemtest.ServeRouter(t, router, routem.Get, "http://localhost/other")
+	assert.Equal(t, http.StatusNotFound, response.Code)
+
+	// ServeMux would redirect to the path with a trailing slash
+	response = routemtest.ServeRouter(t, router, routem.Get, "http://localhost/users")
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
+
+func TestUncleanPathNotFound(t *testing.T) {
+	router := routem.NewRouter(NewHandlerFactory(nil, nil))
+	router.Get("/users/me", routemtest.Echo("me"))
+
+	response := routemtest.ServeRouter(t, router, routem.Get, "http://localhost/users/../users/me")
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
+
+func TestStaticHost(t *testing.T) {
+	router := routem.NewRouter(NewHandlerFactory(nil, nil))
+	router.Get("/", routemtest.Echo("default"))
+	router.WithHost("api.example.com").Get("/", routemtest.Echo("api"))
+
+	response := routemtest.ServeRouter(t, router, routem.Get, "http://api.example.com/")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "api", response.Body.String())
+
+	response = routemtest.ServeRouter(t, router, routem.Get, "http://www.example.com/")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "default", response.Body.String())
+}
+
+func TestHeadServedByGet(t *testing.T) {
+	router := routem.NewRouter(NewHandlerFactory(nil, nil))
+	router.Get("/test", routemtest.Echo("get"))
+
+	response := routemtest.ServeRouter(t, router, routem.Head, "http://localhost/test")
+	assert.Equal(t, http.StatusOK, response.Code)
+}
+
+func TestAllowIncludesHead(t *testing.T) {
+	router := routem.NewRouter(NewHandlerFactory(nil, nil))
+	router.Get("/test", routemtest.Echo("get"))
+	router.Put("/test", routemtest.Echo("put"))
+
+	response := routemtest.ServeRouter(t, router, routem.Delete, "http://localhost/test")
+	assert.Equal(t, http.StatusMethodNotAllowed, response.Code)
+	assert.Equal(t, "GET, HEAD, PUT", response.Header().Get("Allow"))
+}
+
+func TestErrorWithConflictingPatterns(t *testing.T) {
+	routemtest.AssertInvalidPaths(t, NewHandlerFactory, "/:a/b", "/a/:b")
+	routemtest.AssertInvalidPaths(t, NewHandlerFactory, "/users/:user-id")
+	routemtest.AssertInvalidPaths(t, NewHandlerFactory, "/users/:id<int>")
+	routemtest.AssertInvalidPaths(t, NewHandlerFactory, "/users/{id}/{id}")
+}
+
+func TestErrorWithHostPattern(t *testing.T) {
+	router := routem.NewRouter(NewHandlerFactory(nil, nil))
+	router.WithHost(":tenant.example.com").Get("/", routemtest.Echo("tenant"))
+
+	routemtest.AssertInvalid(t, router)
+}
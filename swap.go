@@ -0,0 +1,82 @@
+package routem
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+type (
+	// A SwappableHandler is an http.Handler which serves requests
+	// with a handler that can be replaced while it is serving. Each
+	// request is served entirely by the handler in place when it
+	// arrived, so in-flight requests finish on the old routes.
+	//
+	// Serve a SwappableHandler with Serve() or ServeTLS(), or any
+	// http.Server, to change the routes of a running service.
+	SwappableHandler struct {
+		runnable Runnable
+		current  atomic.Value
+		reload   sync.Mutex
+	}
+
+	// swapped wraps handlers so atomic.Value always stores one type
+	swapped struct {
+		handler http.Handler
+	}
+)
+
+// Compile time type assertion
+var _ http.Handler = &SwappableHandler{}
+
+// NewSwappableHandler constructs a SwappableHandler serving the
+// handler built by the Runnable, which is usually a Router.
+func NewSwappableHandler(runnable Runnable) (*SwappableHandler, error) {
+	handler, err := runnable.Handler()
+
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SwappableHandler{runnable: runnable}
+	s.Swap(handler)
+
+	return s, nil
+}
+
+// Reload rebuilds the handler from the Runnable, picking up any
+// Routes added or removed since it was last built, and swaps it in.
+// If the routes are invalid the error is returned and the current
+// handler is kept.
+//
+// The Runnable must not be reconfigured while Reload is running.
+func (s *SwappableHandler) Reload() error {
+	s.reload.Lock()
+	defer s.reload.Unlock()
+
+	handler, err := s.runnable.Handler()
+
+	if err != nil {
+		return err
+	}
+
+	s.Swap(handler)
+
+	return nil
+}
+
+// Swap replaces the handler serving new requests and returns the
+// handler it replaced.
+func (s *SwappableHandler) Swap(handler http.Handler) http.Handler {
+	old, _ := s.current.Swap(swapped{handler}).(swapped)
+	return old.handler
+}
+
+// Handler returns the handler currently serving new requests.
+func (s *SwappableHandler) Handler() http.Handler {
+	return s.current.Load().(swapped).handler
+}
+
+func (s *SwappableHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	s.Handler().ServeHTTP(response, request)
+}
@@ -0,0 +1,165 @@
+package routem
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const swapAddress = "localhost:9001"
+
+type (
+	// pathHandlerFactory builds handlers which write the paths of
+	// the routes they were built from.
+	pathHandlerFactory struct {
+		error bool
+	}
+)
+
+func (hf *pathHandlerFactory) Handler(routes []Route) (http.Handler, error) {
+	if hf.error {
+		return nil, fmt.Errorf("test error")
+	}
+
+	paths := make([]string, 0, len(routes))
+	for _, route := range routes {
+		paths = append(paths, route.Path())
+	}
+	body := strings.Join(paths, ",")
+
+	return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		fmt.Fprint(response, body)
+	}), nil
+}
+
+func assertSwapBody(t *testing.T, handler http.Handler, body string) {
+	response := httptest.NewRecorder()
+	request, err := http.NewRequest("GET", "http://localhost/", nil)
+	require.Nil(t, err)
+
+	handler.ServeHTTP(response, request)
+
+	assert.Equal(t, body, response.Body.String())
+}
+
+func TestSwappableHandlerReload(t *testing.T) {
+	router := NewRouter(&pathHandlerFactory{})
+	router.Get("/a", testHandler)
+
+	swappable, err := NewSwappableHandler(router)
+	require.Nil(t, err)
+	assertSwapBody(t, swappable, "/a")
+
+	b := router.Get("/b", testHandler)
+	assertSwapBody(t, swappable, "/a")
+
+	require.Nil(t, swappable.Reload())
+	assertSwapBody(t, swappable, "/a,/b")
+
+	router.Remove(b)
+	require.Nil(t, swappable.Reload())
+	assertSwapBody(t, swappable, "/a")
+}
+
+func TestSwappableHandlerReloadError(t *testing.T) {
+	hf := &pathHandlerFactory{}
+	router := NewRouter(hf)
+	router.Get("/a", testHandler)
+
+	swappable, err := NewSwappableHandler(router)
+	require.Nil(t, err)
+
+	hf.error = true
+	router.Get("/b", testHandler)
+
+	assert.NotNil(t, swappable.Reload())
+	assertSwapBody(t, swappable, "/a")
+}
+
+func TestNewSwappableHandlerError(t *testing.T) {
+	swappable, err := NewSwappableHandler(NewRouter(&pathHandlerFactory{error: true}))
+
+	assert.Nil(t, swappable)
+	assert.NotNil(t, err)
+}
+
+func TestSwappableHandlerInFlight(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	old := http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		close(started)
+		<-release
+		fmt.Fprint(response, "old")
+	})
+
+	swappable, err := NewSwappableHandler(NewRouter(&testHandlerFactory{handler: old}))
+	require.Nil(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		assertSwapBody(t, swappable, "old")
+		close(done)
+	}()
+
+	<-started
+	replaced := swappable.Swap(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		fmt.Fprint(response, "new")
+	}))
+	assertSwapBody(t, swappable, "new")
+
+	close(release)
+	<-done
+
+	assert.NotNil(t, replaced)
+}
+
+func TestServeSwappableHandler(t *testing.T) {
+	router := NewRouter(&pathHandlerFactory{})
+	router.Get("/a", testHandler)
+
+	swappable, err := NewSwappableHandler(router)
+	require.Nil(t, err)
+
+	srv, err := Serve(swapAddress, swappable)
+	require.Nil(t, err)
+	defer srv.Stop()
+
+	// Stop leaves open connections to the server running
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	get := func() string {
+		response, err := client.Get("http://" + swapAddress + "/")
+		require.Nil(t, err)
+		defer response.Body.Close()
+
+		body, err := ioutil.ReadAll(response.Body)
+		require.Nil(t, err)
+		return string(body)
+	}
+
+	assert.Equal(t, "/a", get())
+
+	router.Get("/b", testHandler)
+	require.Nil(t, swappable.Reload())
+
+	assert.Equal(t, "/a,/b", get())
+	assert.True(t, srv.IsRunning())
+}
+
+func TestServeWithInvalidAddress(t *testing.T) {
+	srv, err := Serve(invalidAddress, http.NotFoundHandler())
+
+	assert.NotNil(t, err)
+	assert.Nil(t, srv)
+
+	srv, err = ServeTLS(invalidAddress, testCert, testKey, http.NotFoundHandler())
+
+	assert.NotNil(t, err)
+	assert.Nil(t, srv)
+}
@@ -0,0 +1,110 @@
+package trie
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/nick-codes/routem"
+	"github.com/nick-codes/routem/internal/dispatch"
+)
+
+// An EncodedSlashPolicy decides how the trie HandlerFactory treats an
+// encoded slash, "%2F", in a path segment.
+type EncodedSlashPolicy int
+
+const (
+	// DecodeEncodedSlashes decodes an encoded slash along with the
+	// rest of the segment, so the Param value contains a "/". This
+	// is the default.
+	DecodeEncodedSlashes EncodedSlashPolicy = iota
+	// RejectEncodedSlashes responds to paths containing an encoded
+	// slash with a 400 error.
+	RejectEncodedSlashes
+	// PreserveEncodedSlashes decodes the rest of the segment but
+	// leaves encoded slashes as "%2F" in the Param value. A literal
+	// "%" is kept as "%25" so it can not be mistaken for an encoded
+	// slash, and url.PathUnescape recovers the fully decoded value.
+	PreserveEncodedSlashes
+)
+
+const encodedSlash = "%2F"
+
+var encodedSlashError = dispatch.NewRoutingError(http.StatusBadRequest, "Encoded Slash In Path")
+var invalidPathError = dispatch.NewRoutingError(http.StatusBadRequest, "Invalid Path Encoding")
+
+// WithEncodedSlashPolicy sets the EncodedSlashPolicy for the factory.
+func WithEncodedSlashPolicy(policy EncodedSlashPolicy) Option {
+	return func(f *factory) {
+		f.encodedSlashes = policy
+	}
+}
+
+// splitPath splits an escaped path into decoded segments.
+func (f *factory) splitPath(escaped string) ([]string, routem.HTTPError) {
+	parts := strings.Split(escaped, "/")
+
+	for i, part := range parts {
+		if !strings.Contains(part, "%") {
+			continue
+		}
+
+		pieces := []string{part}
+		if strings.Contains(strings.ToUpper(part), encodedSlash) {
+			switch f.encodedSlashes {
+			case RejectEncodedSlashes:
+				return nil, encodedSlashError
+			case PreserveEncodedSlashes:
+				pieces = splitEncodedSlashes(part)
+			}
+		}
+
+		for j, piece := range pieces {
+			decoded, err := url.PathUnescape(piece)
+			if err != nil {
+				return nil, invalidPathError
+			}
+			if f.encodedSlashes == PreserveEncodedSlashes {
+				decoded = strings.ReplaceAll(decoded, "%", "%25")
+			}
+			pieces[j] = decoded
+		}
+
+		parts[i] = strings.Join(pieces, encodedSlash)
+	}
+
+	return parts, nil
+}
+
+// escapeParts escapes decoded segments and joins them into a path.
+func (f *factory) escapeParts(parts []string) string {
+	escaped := make([]string, len(parts))
+
+	for i, part := range parts {
+		// Preserved segments are still escaped
+		if f.encodedSlashes == PreserveEncodedSlashes {
+			if decoded, err := url.PathUnescape(part); err == nil {
+				part = decoded
+			}
+		}
+
+		escaped[i] = url.PathEscape(part)
+	}
+
+	return strings.Join(escaped, "/")
+}
+
+// splitEncodedSlashes splits a segment on encoded slashes of either
+// case.
+func splitEncodedSlashes(segment string) []string {
+	var pieces []string
+
+	for {
+		i := strings.Index(strings.ToUpper(segment), encodedSlash)
+		if i < 0 {
+			return append(pieces, segment)
+		}
+		pieces = append(pieces, segment[:i])
+		segment = segment[i+len(encodedSlash):]
+	}
+}
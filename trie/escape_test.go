@@ -0,0 +1,179 @@
+package trie
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	"github.com/nick-codes/routem"
+
+	"golang.org/x/net/context"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodedSlashDecoded(t *testing.T) {
+	routes := []routem.Route{
+		namedRoute("file", "/files/:name/raw"),
+	}
+
+	response := assertServer(t, routes, routem.Get, "http://localhost/files/a%2Fb/raw")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "file name=a/b", response.Body.String())
+}
+
+func TestEncodedSlashRejected(t *testing.T) {
+	routes := []routem.Route{
+		namedRoute("file", "/files/:name/raw"),
+	}
+
+	factory := NewHandlerFactory(nil, nil, WithEncodedSlashPolicy(RejectEncodedSlashes))
+	response := assertServerFactory(t, factory, routes, routem.Get, "http://localhost/files/a%2fb/raw")
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+
+	response = assertServerFactory(t, factory, routes, routem.Get, "http://localhost/files/a%20b/raw")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "file name=a b", response.Body.String())
+}
+
+func TestEncodedSlashRejectedErrorHandler(t *testing.T) {
+	routes := []routem.Route{
+		namedRoute("file", "/files/:name"),
+	}
+
+	var code int
+	factory := NewHandlerFactory(nil, func(err routem.HTTPError, ctx context.Context) error {
+		code = err.Code()
+		return nil
+	}, WithEncodedSlashPolicy(RejectEncodedSlashes))
+	assertServerFactory(t, factory, routes, routem.Get, "http://localhost/files/a%2Fb")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestEncodedSlashPreserved(t *testing.T) {
+	routes := []routem.Route{
+		namedRoute("file", "/files/:name/raw"),
+		namedRoute("rest", "/static/*rest"),
+	}
+
+	factory := NewHandlerFactory(nil, nil, WithEncodedSlashPolicy(PreserveEncodedSlashes))
+	response := assertServerFactory(t, factory, routes, routem.Get, "http://localhost/files/a%2fb%20c/raw")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "file name=a%2Fb c", response.Body.String())
+
+	response = assertServerFactory(t, factory, routes, routem.Get, "http://localhost/static/a%2Fb/c%3F")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "rest rest=a%2Fb/c?", response.Body.String())
+
+	// A literal "%2F" is told apart from an encoded slash
+	response = assertServerFactory(t, factory, routes, routem.Get, "http://localhost/files/a%252Fb/raw")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "file name=a%252Fb", response.Body.String())
+}
+
+func TestEncodedStaticSegment(t *testing.T) {
+	routes := []routem.Route{
+		namedRoute("space", "/hello world"),
+	}
+
+	response := assertServer(t, routes, routem.Get, "http://localhost/hello%20world")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "space", response.Body.String())
+}
+
+func TestInvalidRawPathIgnored(t *testing.T) {
+	routes := []routem.Route{
+		namedRoute("file", "/files/:name"),
+	}
+
+	handler, err := NewHandlerFactory(nil, nil).Handler(routes)
+	require.Nil(t, err)
+
+	response := httptest.NewRecorder()
+	request := &http.Request{
+		Method: "GET",
+		URL:    &url.URL{Path: "/files/%zz", RawPath: "/files/%zz"},
+	}
+	handler.ServeHTTP(response, request)
+
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "file name=%zz", response.Body.String())
+}
+
+func TestCaseInsensitiveRedirectEscaped(t *testing.T) {
+	routes := []routem.Route{
+		namedRoute("file", "/Files/:name"),
+	}
+
+	factory := NewHandlerFactory(nil, nil, WithCaseInsensitiveRedirect())
+	response := assertServerFactory(t, factory, routes, routem.Get, "http://localhost/files/a%2Fb%20c")
+	assert.Equal(t, http.StatusMovedPermanently, response.Code)
+	assert.Equal(t, "/Files/a%2Fb%20c", response.Header().Get("Location"))
+}
+
+func FuzzEncodedParams(f *testing.F) {
+	f.Add("a", "b")
+	f.Add("a/b", "c")
+	f.Add("a%2Fb", "%")
+	f.Add("?#", " +")
+	f.Add("..", ".")
+	f.Add("%252F", "/%2f/")
+
+	routes := []routem.Route{
+		namedRoute("params", "/f/:first/:second"),
+	}
+
+	// Each policy must recover the values, or reject encoded slashes
+	policies := []struct {
+		policy EncodedSlashPolicy
+		value  func(string) string
+	}{
+		{DecodeEncodedSlashes, func(v string) string { return v }},
+		{RejectEncodedSlashes, func(v string) string { return v }},
+		{PreserveEncodedSlashes, func(v string) string {
+			return strings.ReplaceAll(strings.ReplaceAll(v, "%", "%25"), "/", encodedSlash)
+		}},
+	}
+
+	handlers := make([]http.Handler, len(policies))
+	for i, p := range policies {
+		handler, err := NewHandlerFactory(nil, nil, WithEncodedSlashPolicy(p.policy)).Handler(routes)
+		require.Nil(f, err)
+		handlers[i] = handler
+	}
+
+	f.Fuzz(func(t *testing.T, first, second string) {
+		if len(first) == 0 || len(second) == 0 {
+			return
+		}
+
+		path := "/f/" + url.PathEscape(first) + "/" + url.PathEscape(second)
+		request, err := http.NewRequest("GET", "http://localhost"+path, nil)
+		if err != nil {
+			return
+		}
+
+		for i, p := range policies {
+			response := httptest.NewRecorder()
+			handlers[i].ServeHTTP(response, request)
+
+			if p.policy == RejectEncodedSlashes && strings.Contains(first+second, "/") {
+				require.Equal(t, http.StatusBadRequest, response.Code, path)
+				continue
+			}
+
+			require.Equal(t, http.StatusOK, response.Code, path)
+			require.Equal(t, "params first="+p.value(first)+" second="+p.value(second), response.Body.String(), path)
+
+			if p.policy == PreserveEncodedSlashes {
+				decoded, err := url.PathUnescape(p.value(first))
+				require.Nil(t, err)
+				require.Equal(t, first, decoded, path)
+			}
+		}
+	})
+}
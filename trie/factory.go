@@ -3,9 +3,11 @@ package trie
 import (
 	"fmt"
 	"net/http"
+	"regexp"
 	"strings"
 
 	"github.com/nick-codes/routem"
+	"github.com/nick-codes/routem/internal/dispatch"
 
 	"golang.org/x/net/context"
 )
@@ -15,31 +17,81 @@ var _ http.Handler = &rootNode{}
 var _ routem.HandlerFactory = &factory{}
 
 type routeInfo struct {
-	route   routem.Route
-	params  map[int]string
-	handler routem.HandlerFunc
+	route    routem.Route
+	params   map[int]string
+	catchAll int // depth of a trailing catch-all segment, zero if none
+	handler  routem.HandlerFunc
 }
 
 type rootNode struct {
-	ctx          context.Context
-	errorHandler routem.ErrorHandlerFunc
-	node
+	factory
+	node  // routes without a host
+	hosts []*hostNode
+
+	dispatcher dispatch.Dispatcher
 }
 
 type node struct {
 	path     string
 	routes   map[routem.Method]*routeInfo
-	children map[string]*node
+	children map[string]*node // static children keyed by segment
+	params   []*node          // parameter children, constrained first
+	catchAll *node
+
+	constraint string // the constraint of a parameter node
+	pattern    *regexp.Regexp
+	nonEmpty   bool // the parameter rejects an empty segment
 }
 
 type factory struct {
 	ctx          context.Context
 	errorHandler routem.ErrorHandlerFunc
+	autoOptions  bool
+	autoHead     bool
+	panicHandler func(routem.PanicError, context.Context)
+	pathPolicy   PathPolicy
+	redirectCode int
+	foldCase     bool
+
+	encodedSlashes EncodedSlashPolicy
 }
 
 // Constructs a new handler factory which uses a trie data structure
 // to quickly look up routes.
 //
+// Path segments are either literal, a named parameter such as
+// ":id" which matches any single segment, or a trailing catch-all
+// such as "*filepath" which matches the remainder of the path,
+// slashes included. A catch-all must be the last segment of a route.
+//
+// A parameter may be constrained by following its name with a
+// regular expression, as in ":slug<[a-z0-9-]+>", or one of the named
+// constraints int, uint or uuid, as in ":id<int>". The expression
+// must match the whole segment. Segments which fail to match fall
+// through to other routes. Constrained parameters take precedence
+// over unconstrained ones in the order their routes were given.
+//
+// Routes are matched against the escaped path of the request one
+// segment at a time, with each segment decoded on its own. A
+// parameter value may therefore contain an encoded slash, "%2F",
+// without splitting the segment. How encoded slashes are treated can
+// be changed with WithEncodedSlashPolicy.
+//
+// Routes with a Host() only match requests for that host. A host
+// pattern is made of literal labels and named parameters such as
+// ":tenant.example.com", whose values are added to the Params along
+// with the path parameters. Any port in the request host is ignored.
+// Requests which match no host pattern with a route for their path
+// fall back to the routes without a host.
+//
+// When more than one route could match a request a static segment
+// takes precedence over a parameter, which in turn takes precedence
+// over a catch-all. Lookup backtracks when a more specific branch
+// fails to match the rest of the path, so the result does not depend
+// on registration order, except between constrained parameters whose
+// constraints overlap, which are tried in the order their routes were
+// given.
+//
 // All routes will be passed a context
 // derived from the context passed to the factory. If no context is
 // passed then context.Background() is used as the root context.
@@ -47,14 +99,34 @@ type factory struct {
 // If an ErrorHandlerFunc is provided and the route does not have a route
 // specific error handler that handler will be called if a route
 // returns an error. Otherwise a 500 error will be returned to the client.
-func NewHandlerFactory(ctx context.Context, errorHandler routem.ErrorHandlerFunc) routem.HandlerFactory {
+//
+// When a path exists but has no route for the requested method a 405
+// error is passed to the error handlers instead of a 404 and the Allow
+// header of the response lists the methods the path can be served with.
+//
+// Each handler runs against its route's Timeout(). Once the timeout
+// expires writes to the response fail with routem.ErrHandlerTimeout
+// and a 408 error is passed to the error handlers, unless the handler
+// had already committed the response in which case nothing more is
+// sent. The response writer handed to handlers reports this as a
+// routem.CommitReporter.
+//
+// A panic in a route handler is recovered and passed to the error
+// handlers as a routem.PanicError.
+//
+// Further behaviour can be enabled by passing Options.
+func NewHandlerFactory(ctx context.Context, errorHandler routem.ErrorHandlerFunc, options ...Option) routem.HandlerFactory {
 	if ctx == nil {
 		ctx = context.Background()
 	}
-	return &factory{
+	f := &factory{
 		ctx:          ctx,
 		errorHandler: errorHandler,
 	}
+	for _, option := range options {
+		option(f)
+	}
+	return f
 }
 
 func (f *factory) Handler(routes []routem.Route) (http.Handler, error) {
@@ -64,46 +136,71 @@ func (f *factory) Handler(routes []routem.Route) (http.Handler, error) {
 	}
 
 	root := &rootNode{
-		ctx:          f.ctx,
-		errorHandler: f.errorHandler,
+		factory: *f,
+		dispatcher: dispatch.Dispatcher{
+			Context:      f.ctx,
+			ErrorHandler: f.errorHandler,
+			PanicHandler: f.panicHandler,
+		},
 		node: node{
 			path:     "",
 			children: make(map[string]*node),
 			routes:   make(map[routem.Method]*routeInfo),
 		},
 	}
+	hosts := make(map[string]*hostNode)
 
 	for _, route := range routes {
-		if route == nil {
-			return nil, fmt.Errorf("Received a nil route.")
+		if err := dispatch.CheckRoute(route); err != nil {
+			return nil, err
 		}
 
-		if len(route.Path()) == 0 {
-			return nil, fmt.Errorf("Received a zero length path.")
-		}
+		tree := &root.node
+		parts := strings.Split(route.Path(), "/")
+		params := make(map[string]struct{}, len(parts))
 
-		if strings.Contains(route.Path(), "//") {
-			return nil, fmt.Errorf("Route contains an invalid path: %s", route.Path())
-		}
+		if len(route.Host()) > 0 {
+			host, err := newHostNode(route.Host())
 
-		if !strings.HasPrefix(route.Path(), "/") {
-			return nil, fmt.Errorf("Route does not begin with a slash: %s", route.Path())
-		}
+			if err != nil {
+				return nil, err
+			}
 
-		parts := strings.Split(route.Path(), "/")
-		params := make(map[string]struct{}, len(parts))
+			// Patterns which only differ in parameter names would
+			// match the same hosts
+			shape := strings.Join(host.labels, ".")
+			if existing, exists := hosts[shape]; exists {
+				if existing.pattern != host.pattern {
+					return nil, fmt.Errorf("Conflicting hosts: %s - %s", host.pattern, existing.pattern)
+				}
+				host = existing
+			} else {
+				hosts[shape] = host
+				root.hosts = append(root.hosts, host)
+			}
 
-		for _, part := range parts {
-			if strings.HasPrefix(part, ":") {
-				_, exists := params[part]
+			for _, name := range host.params {
+				params[name] = struct{}{}
+			}
+
+			tree = &host.node
+		}
+
+		for i, part := range parts {
+			if strings.HasPrefix(part, "*") && i != len(parts)-1 {
+				return nil, fmt.Errorf("Catch-all must be the last segment: %s", route.Path())
+			}
+			if strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*") {
+				name, _ := splitParam(part)
+				_, exists := params[name]
 				if exists {
 					return nil, fmt.Errorf("Route has duplicate parameter: %s", part)
 				}
-				params[part] = struct{}{}
+				params[name] = struct{}{}
 			}
 		}
 
-		inserted, err := root.insert(parts, route, 0, nil)
+		inserted, err := tree.insert(parts, route, 0, nil)
 
 		if err != nil {
 			return nil, err
@@ -115,6 +212,16 @@ func (f *factory) Handler(routes []routem.Route) (http.Handler, error) {
 		}
 	}
 
+	sortHosts(root.hosts)
+
+	// An empty segment is left for the PathPolicy to correct
+	if root.pathPolicy != StrictPaths {
+		root.rejectEmpty()
+		for _, host := range root.hosts {
+			host.rejectEmpty()
+		}
+	}
+
 	return root, nil
 }
 
@@ -123,13 +230,34 @@ func newNode(path string) (*node, error) {
 	var err error
 
 	if strings.HasPrefix(path, ":") {
-		paramName := strings.TrimPrefix(path, ":")
+		paramName, constraint := splitParam(path)
 
 		if len(paramName) == 0 {
 			err = fmt.Errorf("Found an un-named parameter: %s", path)
+		} else {
+			var pattern *regexp.Regexp
+			pattern, err = parseConstraint(path, constraint)
+
+			if err == nil {
+				ret = &node{
+					path:       ":",
+					routes:     make(map[routem.Method]*routeInfo),
+					children:   make(map[string]*node),
+					constraint: constraint,
+					pattern:    pattern,
+				}
+			}
+		}
+	} else if strings.HasPrefix(path, "*") {
+		paramName, constraint := splitParam(path)
+
+		if len(paramName) == 0 {
+			err = fmt.Errorf("Found an un-named catch-all: %s", path)
+		} else if len(constraint) > 0 {
+			err = fmt.Errorf("Catch-all can not be constrained: %s", path)
 		} else {
 			ret = &node{
-				path:     ":",
+				path:     "*",
 				routes:   make(map[routem.Method]*routeInfo),
 				children: make(map[string]*node),
 			}
@@ -145,19 +273,59 @@ func newNode(path string) (*node, error) {
 	return ret, err
 }
 
+// child returns the existing child which would hold the given
+// segment or nil if there is no such child yet.
+func (n *node) child(segment string) *node {
+	switch {
+	case strings.HasPrefix(segment, ":"):
+		_, constraint := splitParam(segment)
+		for _, child := range n.params {
+			if child.constraint == constraint {
+				return child
+			}
+		}
+		return nil
+	case strings.HasPrefix(segment, "*"):
+		return n.catchAll
+	default:
+		return n.children[segment]
+	}
+}
+
+// addChild hangs a new child in the slot for its kind of segment.
+func (n *node) addChild(child *node) {
+	switch child.path {
+	case ":":
+		// Unconstrained parameters go last
+		i := len(n.params)
+		if len(child.constraint) > 0 {
+			for i > 0 && len(n.params[i-1].constraint) == 0 {
+				i--
+			}
+		}
+		n.params = append(n.params, nil)
+		copy(n.params[i+1:], n.params[i:])
+		n.params[i] = child
+	case "*":
+		n.catchAll = child
+	default:
+		n.children[child.path] = child
+	}
+}
+
 func (n *node) insert(parts []string, route routem.Route, depth int, params map[int]string) (bool, error) {
 
 	inserted := false
 	var err error
 
-	// Is this a parameter segment?
+	// Is this a parameter or catch-all segment?
 	thisPath := parts[0]
-	if strings.HasPrefix(thisPath, ":") {
+	if strings.HasPrefix(thisPath, ":") || strings.HasPrefix(thisPath, "*") {
 		if params == nil {
 			params = make(map[int]string, len(parts))
 		}
-		params[depth] = strings.TrimPrefix(thisPath, ":")
-		thisPath = ":"
+		params[depth], _ = splitParam(thisPath)
+		thisPath = thisPath[:1]
 	}
 
 	// Does this belong in this sub-tree?
@@ -175,18 +343,15 @@ func (n *node) insert(parts []string, route routem.Route, depth int, params map[
 
 			// No? Then do the insert
 			if err == nil {
-				// Build the middleware stack
-				handler := route.Handler()
-				middlewares := route.Middlewares()
-				for i := len(middlewares) - 1; i >= 0; i-- {
-					handler = middlewares[i](handler)
-				}
-
 				// Remember all the info for the route
 				info := &routeInfo{
 					route:   route,
 					params:  params,
-					handler: handler,
+					handler: dispatch.Handler(route),
+				}
+
+				if n.path == "*" {
+					info.catchAll = depth
 				}
 
 				// Set it on the various methods
@@ -198,65 +363,107 @@ func (n *node) insert(parts []string, route routem.Route, depth int, params map[
 			}
 		} else {
 
-			// Check if we can insert in any existing children
-			for _, child := range n.children {
-				inserted, err = child.insert(parts[1:], route, depth+1, params)
-				if inserted || err != nil {
-					break
-				}
-			}
-
-			// Okay, then make a new child and insert
-			if !inserted && err == nil {
-				newChild, err := newNode(parts[1])
-
+			// Find or make the child for the next segment and insert there
+			child := n.child(parts[1])
+			if child == nil {
+				child, err = newNode(parts[1])
 				if err == nil {
-					n.children[newChild.path] = newChild
-					inserted, err = newChild.insert(parts[1:], route, depth+1, params)
+					n.addChild(child)
 				}
 			}
 
+			if err == nil {
+				inserted, err = child.insert(parts[1:], route, depth+1, params)
+			}
 		}
 	}
 
 	return inserted, err
 }
 
-var routeNotFoundError = routem.NewHTTPError(http.StatusNotFound, fmt.Errorf("No Such Route"))
-
+// find looks up the route for the given path parts and method.
+//
+// At every level the children are tried in a fixed order of
+// precedence: the static child with an exactly matching segment
+// first, then the parameter child and finally the catch-all. If a
+// descent fails to produce a route the search backtracks and tries
+// the next child, so "/users/me/settings" and "/users/:id/posts"
+// can both be served.
 func (n *node) find(parts []string, method routem.Method) (*routeInfo, routem.HTTPError) {
 	var info *routeInfo = nil
 	var err routem.HTTPError = nil
 
 	// Did we fish our wish?
-	if n.path == ":" || parts[0] == n.path {
+	if n.matches(parts[0]) {
 
-		// Did we run out of parts?
-		if len(parts) == 1 {
+		// Did we run out of parts or hit a catch-all?
+		if len(parts) == 1 || n.path == "*" {
 			info = n.routes[method]
 		} else {
 
-			// Search all the children
+			// Search the children in order of precedence and
+			// return the first thing we find up the stack
 			subParts := parts[1:]
-			for _, child := range n.children {
-				info, err = child.find(subParts, method)
-
-				// If we found something return it up the stack
-				if info != nil {
-					break
-				}
-			}
+			n.each(subParts[0], func(child *node) bool {
+				info, _ = child.find(subParts, method)
+				return info != nil
+			})
 
 		}
 	}
 
 	if info == nil {
-		err = routeNotFoundError
+		err = dispatch.RouteNotFound
 	}
 
 	return info, err
 }
 
+// allowed collects the routes for every method registered on any
+// node matching the given path parts. It follows every matching
+// branch so the result covers all methods the path can be served
+// with.
+func (n *node) allowed(parts []string, allowed map[routem.Method]*routeInfo) {
+	if !n.matches(parts[0]) {
+		return
+	}
+
+	if len(parts) == 1 || n.path == "*" {
+		for method, info := range n.routes {
+			if allowed[method] == nil {
+				allowed[method] = info
+			}
+		}
+		return
+	}
+
+	subParts := parts[1:]
+	n.each(subParts[0], func(child *node) bool {
+		child.allowed(subParts, allowed)
+		return false
+	})
+}
+
+func (n *node) allowedRoutes(parts []string) map[routem.Method]*routeInfo {
+	allowed := make(map[routem.Method]*routeInfo)
+	n.allowed(parts, allowed)
+	return allowed
+}
+
+// allowHeader builds a sorted value for the Allow header including
+// any methods which are answered automatically. It also returns the
+// route for the first registered method.
+func (root *rootNode) allowHeader(allowed map[routem.Method]*routeInfo) (string, *routeInfo) {
+	var extra []routem.Method
+	if root.autoHead && allowed[routem.Get] != nil {
+		extra = append(extra, routem.Head)
+	}
+	if root.autoOptions {
+		extra = append(extra, routem.Options)
+	}
+	return dispatch.AllowHeader(allowed, extra...)
+}
+
 func routeParams(route *routeInfo, parts []string) routem.Params {
 	var params routem.Params
 
@@ -266,7 +473,11 @@ func routeParams(route *routeInfo, parts []string) routem.Params {
 		for index, param := range route.params {
 			// Route cannot match unless it is of sufficient length, so we are sure
 			// index  < len(parts) at this point.
-			params[param] = parts[index]
+			if index == route.catchAll {
+				params[param] = strings.Join(parts[index:], "/")
+			} else {
+				params[param] = parts[index]
+			}
 		}
 	}
 
@@ -274,48 +485,75 @@ func routeParams(route *routeInfo, parts []string) routem.Params {
 }
 
 func (root *rootNode) ServeHTTP(response http.ResponseWriter, request *http.Request) {
-	parts := strings.Split(request.URL.Path, "/")
-	routeInfo, err := root.find(parts, routem.Method(request.Method))
+	method := routem.Method(request.Method)
+	parts, err := root.splitPath(request.URL.EscapedPath())
 
-	timeout := routem.DefaultTimeout
-	if err == nil {
-		timeout = routeInfo.route.Timeout()
+	if err != nil {
+		root.dispatcher.Fail(response, request, nil, err, nil)
+		return
 	}
 
-	ctx, cancel := routem.NewRequestContext(
-		root.ctx, timeout, request, response,
-		routeParams(routeInfo, parts))
+	tree, host, labels := root.tree(request.Host, parts)
+	routeInfo, err := tree.find(parts, method)
 
-	defer cancel()
+	// The path may only exist in a canonical form
+	if err != nil && (root.pathPolicy != StrictPaths || root.foldCase) && !tree.hasRoutes(parts) {
+		if fixed, redirect := root.fixPath(request.Host, request.URL.EscapedPath()); len(fixed) > 0 {
+			if redirect || root.pathPolicy == RedirectPaths {
+				root.redirect(response, request, fixed)
+				return
+			}
 
-	if err == nil {
-		complete := make(chan routem.HTTPError)
-		go func() {
-			complete <- routeInfo.handler(ctx)
-		}()
-
-		select {
-		case <-ctx.Done():
-			err = routem.NewHTTPError(408, fmt.Errorf("Request Timed Out!"))
-		case err = <-complete:
+			// fixPath only returns paths which split
+			parts, _ = root.splitPath(fixed)
+			tree, host, labels = root.tree(request.Host, parts)
+			routeInfo, err = tree.find(parts, method)
 		}
 	}
 
+	// The path may still exist for other methods. The first route
+	// in the Allow header provides the configuration used to handle
+	// the error.
 	if err != nil {
-		var errErr error
-
-		if routeInfo != nil && routeInfo.route.ErrorHandler() != nil {
-			errErr = routeInfo.route.ErrorHandler()(err, ctx)
-		} else if root.errorHandler != nil {
-			errErr = root.errorHandler(err, ctx)
-		} else if err == routeNotFoundError {
-			http.Error(response, fmt.Sprintf("Route Not Found: %s", errErr), http.StatusNotFound)
-		} else {
-			errErr = err
+		allowed := tree.allowedRoutes(parts)
+		if root.autoHead && method == routem.Head && allowed[routem.Get] != nil {
+			routeInfo, err = allowed[routem.Get], nil
+			response = headResponseWriter{response}
+		} else if len(allowed) > 0 {
+			var allow string
+			allow, routeInfo = root.allowHeader(allowed)
+			response.Header().Set("Allow", allow)
+
+			if root.autoOptions && method == routem.Options {
+				response.WriteHeader(http.StatusOK)
+				return
+			}
+
+			err = dispatch.MethodNotAllowed
+		}
+	}
+
+	params := routeParams(routeInfo, parts)
+	if host != nil {
+		params = host.hostParams(labels, params)
+	}
+
+	if err == nil {
+		var committed bool
+		err, committed = root.dispatcher.Run(routeInfo.route, routeInfo.handler, request, response, params)
+
+		// Nothing more can be sent once a timed out handler has
+		// started the response
+		if committed {
+			return
 		}
+	}
 
-		if errErr != nil {
-			http.Error(response, fmt.Sprintf("Internal Server Error: %s", errErr), http.StatusInternalServerError)
+	if err != nil {
+		var route routem.Route
+		if routeInfo != nil {
+			route = routeInfo.route
 		}
+		root.dispatcher.Fail(response, request, route, err, params)
 	}
 }
@@ -4,15 +4,18 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sort"
 	"time"
 
 	"github.com/nick-codes/routem"
+	"github.com/nick-codes/routem/routemtest"
 
 	"golang.org/x/net/context"
 
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type (
@@ -26,10 +29,12 @@ const (
 )
 
 type testRoute struct {
+	host         string
 	path         string
 	method       []routem.Method
 	handler      routem.HandlerFunc
 	errorHandler routem.ErrorHandlerFunc
+	timeout      time.Duration
 }
 
 func (t *testRoute) Handler() routem.HandlerFunc {
@@ -93,7 +98,10 @@ func (t *testRoute) Middlewares() []routem.MiddlewareFunc {
 	}
 }
 
-func (*testRoute) Timeout() time.Duration {
+func (t *testRoute) Timeout() time.Duration {
+	if t.timeout > 0 {
+		return t.timeout
+	}
 	return routem.DefaultTimeout
 }
 
@@ -105,6 +113,18 @@ func (r *testRoute) Path() string {
 	return r.path
 }
 
+func (r *testRoute) Host() string {
+	return r.host
+}
+
+func (r *testRoute) WithName(string) routem.Route {
+	return r
+}
+
+func (*testRoute) Name() string {
+	return ""
+}
+
 // Helpers
 func assertError(t *testing.T, routes []routem.Route) {
 	factory := NewHandlerFactory(nil, nil)
@@ -301,13 +321,67 @@ func TestTimeout(t *testing.T) {
 	<-done
 }
 
-func TestRouteNotFoundMethod(t *testing.T) {
+func TestRouteMethodNotAllowed(t *testing.T) {
 	routes := []routem.Route{
 		&testRoute{path: "/test"},
 	}
 
 	response := assertServer(t, routes, routem.Put, "http://localhost/test")
-	assert.Equal(t, 404, response.Code)
+	assert.Equal(t, 405, response.Code)
+	assert.Equal(t, "GET", response.Header().Get("Allow"))
+}
+
+func TestMethodNotAllowedAllowUnion(t *testing.T) {
+	routes := []routem.Route{
+		&testRoute{path: "/users/me"},
+		&testRoute{path: "/users/me", method: routem.DeleteMethod},
+		&testRoute{path: "/users/:id", method: routem.PutMethod},
+		&testRoute{path: "/users/*rest", method: routem.PostMethod},
+		&testRoute{path: "/users/:id/posts", method: routem.PatchMethod},
+	}
+
+	response := assertServer(t, routes, routem.Options, "http://localhost/users/me")
+	assert.Equal(t, 405, response.Code)
+	assert.Equal(t, "DELETE, GET, POST, PUT", response.Header().Get("Allow"))
+
+	response = assertServer(t, routes, routem.Get, "http://localhost/users/42")
+	assert.Equal(t, 405, response.Code)
+	assert.Equal(t, "POST, PUT", response.Header().Get("Allow"))
+}
+
+func TestMethodNotAllowedRouteErrorHandler(t *testing.T) {
+	var code int
+	routes := []routem.Route{
+		&testRoute{
+			path: "/test",
+			errorHandler: func(err routem.HTTPError, ctx context.Context) error {
+				code = err.Code()
+				response := routem.ResponseWriterFromContext(ctx)
+				http.Error(response, "Custom Page", err.Code())
+				return nil
+			},
+		},
+	}
+
+	response := assertServer(t, routes, routem.Put, "http://localhost/test")
+	assert.Equal(t, 405, code)
+	assert.Equal(t, 405, response.Code)
+	assert.Equal(t, "GET", response.Header().Get("Allow"))
+	assert.Equal(t, "Custom Page\n", response.Body.String())
+}
+
+func TestMethodNotAllowedFactoryErrorHandler(t *testing.T) {
+	routes := []routem.Route{
+		&testRoute{path: "/test"},
+	}
+	factory := NewHandlerFactory(context.Background(), func(err routem.HTTPError, ctx context.Context) error {
+		response := routem.ResponseWriterFromContext(ctx)
+		http.Error(response, "Converted to 400", 400)
+		return nil
+	})
+	response := assertServerFactory(t, factory, routes, routem.Put, "http://localhost/test")
+	assert.Equal(t, 400, response.Code)
+	assert.Equal(t, "GET", response.Header().Get("Allow"))
 }
 
 func TestDeepRouteNotFoundMethod(t *testing.T) {
@@ -398,3 +472,276 @@ func TestRootContextPassed(t *testing.T) {
 	assertServerFactory(t, factory, routes, routem.Get, "http://localhost/test")
 	assert.True(t, called)
 }
+
+func TestErrorWithUnnamedCatchAll(t *testing.T) {
+	routes := []routem.Route{
+		&testRoute{path: "/static/*"},
+	}
+	assertError(t, routes)
+}
+
+func TestErrorWithCatchAllNotLast(t *testing.T) {
+	routes := []routem.Route{
+		&testRoute{path: "/static/*filepath/more"},
+	}
+	assertError(t, routes)
+}
+
+func TestErrorWithDuplicateCatchAllRoutes(t *testing.T) {
+	routes := []routem.Route{
+		&testRoute{path: "/static/*filepath"},
+		&testRoute{path: "/static/*rest"},
+	}
+	assertError(t, routes)
+}
+
+func TestErrorWithCatchAllDuplicateParamName(t *testing.T) {
+	routes := []routem.Route{
+		&testRoute{path: "/:name/*name"},
+	}
+	assertError(t, routes)
+}
+
+func TestCatchAllRouting(t *testing.T) {
+	var filepath string
+	routes := []routem.Route{
+		&testRoute{
+			path: "/static/*filepath",
+			handler: func(ctx context.Context) routem.HTTPError {
+				filepath = routem.ParamsFromContext(ctx)["filepath"]
+				return nil
+			},
+		},
+	}
+
+	response := assertServer(t, routes, routem.Get, "http://localhost/static/css/site/main.css")
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, "css/site/main.css", filepath)
+
+	response = assertServer(t, routes, routem.Get, "http://localhost/static/")
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, "", filepath)
+
+	response = assertServer(t, routes, routem.Get, "http://localhost/static")
+	assert.Equal(t, 404, response.Code)
+}
+
+func TestCatchAllWithParamRouting(t *testing.T) {
+	called := false
+	routes := []routem.Route{
+		&testRoute{
+			path: "/proxy/:host/*rest",
+			handler: func(ctx context.Context) routem.HTTPError {
+				params := routem.ParamsFromContext(ctx)
+				assert.Equal(t, "example.com", params["host"])
+				assert.Equal(t, "a/b/c", params["rest"])
+				called = true
+				return nil
+			},
+		},
+	}
+
+	response := assertServer(t, routes, routem.Get, "http://localhost/proxy/example.com/a/b/c")
+
+	assert.True(t, called)
+	assert.Equal(t, 200, response.Code)
+}
+
+// namedRoute builds a testRoute which writes its name and the sorted
+// parameters it received to the response body.
+func namedRoute(name, path string) routem.Route {
+	return &testRoute{
+		path: path,
+		handler: func(ctx context.Context) routem.HTTPError {
+			params := routem.ParamsFromContext(ctx)
+			keys := make([]string, 0, len(params))
+			for key := range params {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+
+			body := name
+			for _, key := range keys {
+				body += fmt.Sprintf(" %s=%s", key, params[key])
+			}
+
+			fmt.Fprint(routem.ResponseWriterFromContext(ctx), body)
+			return nil
+		},
+	}
+}
+
+func permutations(routes []routem.Route) [][]routem.Route {
+	if len(routes) <= 1 {
+		return [][]routem.Route{routes}
+	}
+
+	var result [][]routem.Route
+	for i := range routes {
+		rest := make([]routem.Route, 0, len(routes)-1)
+		rest = append(rest, routes[:i]...)
+		rest = append(rest, routes[i+1:]...)
+		for _, perm := range permutations(rest) {
+			result = append(result, append([]routem.Route{routes[i]}, perm...))
+		}
+	}
+	return result
+}
+
+func TestAmbiguousPrecedenceAllOrders(t *testing.T) {
+	routes := []routem.Route{
+		namedRoute("me", "/users/me"),
+		namedRoute("id", "/users/:id"),
+		namedRoute("settings", "/users/me/settings"),
+		namedRoute("posts", "/users/:id/posts"),
+		namedRoute("rest", "/users/*rest"),
+		namedRoute("about", "/:section/about"),
+	}
+
+	cases := []struct {
+		url  string
+		code int
+		body string
+	}{
+		{"/users/me", 200, "me"},
+		{"/users/42", 200, "id id=42"},
+		{"/users/me/settings", 200, "settings"},
+		{"/users/me/posts", 200, "posts id=me"},
+		{"/users/42/posts", 200, "posts id=42"},
+		{"/users/42/other", 200, "rest rest=42/other"},
+		{"/users/me/settings/more", 200, "rest rest=me/settings/more"},
+		{"/users/me/about", 200, "rest rest=me/about"},
+		{"/users/about", 200, "id id=about"},
+		{"/team/about", 200, "about section=team"},
+		{"/team", 404, ""},
+		{"/team/about/more", 404, ""},
+	}
+
+	for _, perm := range permutations(routes) {
+		handler, err := NewHandlerFactory(nil, nil).Handler(perm)
+		require.Nil(t, err)
+
+		for _, c := range cases {
+			response := httptest.NewRecorder()
+			request, err := http.NewRequest("GET", "http://localhost"+c.url, nil)
+			require.Nil(t, err)
+
+			handler.ServeHTTP(response, request)
+
+			require.Equal(t, c.code, response.Code, c.url)
+			if c.code == 200 {
+				require.Equal(t, c.body, response.Body.String(), c.url)
+			}
+		}
+	}
+}
+
+func TestAmbiguousCatchAllAndStatic(t *testing.T) {
+	routes := []routem.Route{
+		namedRoute("catchall", "/static/*filepath"),
+		namedRoute("robots", "/static/robots.txt"),
+		namedRoute("param", "/static/:file/raw"),
+	}
+
+	cases := []struct {
+		url  string
+		body string
+	}{
+		{"/static/robots.txt", "robots"},
+		{"/static/robots.txt/raw", "param file=robots.txt"},
+		{"/static/main.css", "catchall filepath=main.css"},
+		{"/static/main.css/raw", "param file=main.css"},
+		{"/static/main.css/raw/more", "catchall filepath=main.css/raw/more"},
+		{"/static/", "catchall filepath="},
+	}
+
+	for _, perm := range permutations(routes) {
+		for _, c := range cases {
+			response := assertServer(t, perm, routem.Get, "http://localhost"+c.url)
+			assert.Equal(t, 200, response.Code, c.url)
+			assert.Equal(t, c.body, response.Body.String(), c.url)
+		}
+	}
+}
+
+func TestAmbiguousParamBacktracksOnMethod(t *testing.T) {
+	routes := []routem.Route{
+		namedRoute("me", "/users/me"),
+		&testRoute{path: "/users/:id", method: routem.PutMethod},
+	}
+
+	for _, perm := range permutations(routes) {
+		response := assertServer(t, perm, routem.Put, "http://localhost/users/me")
+		assert.Equal(t, 200, response.Code)
+	}
+}
+
+func TestPanicRecovered(t *testing.T) {
+	routes := []routem.Route{
+		&testRoute{
+			path: "/test",
+			handler: func(ctx context.Context) routem.HTTPError {
+				panic("boom")
+			},
+		},
+	}
+
+	response := assertServer(t, routes, routem.Get, "http://localhost/test")
+	assert.Equal(t, 500, response.Code)
+}
+
+func TestPanicPassedToErrorHandler(t *testing.T) {
+	var panicErr routem.PanicError
+	routes := []routem.Route{
+		&testRoute{
+			path: "/test",
+			handler: func(ctx context.Context) routem.HTTPError {
+				panic("boom")
+			},
+			errorHandler: func(err routem.HTTPError, ctx context.Context) error {
+				panicErr, _ = err.(routem.PanicError)
+				http.Error(routem.ResponseWriterFromContext(ctx), "Oops", err.Code())
+				return nil
+			},
+		},
+	}
+
+	response := assertServer(t, routes, routem.Get, "http://localhost/test")
+	assert.Equal(t, 500, response.Code)
+	assert.Equal(t, "Oops\n", response.Body.String())
+	require.NotNil(t, panicErr)
+	assert.Equal(t, "boom", panicErr.Value())
+	assert.NotEmpty(t, panicErr.Stack())
+}
+
+func BenchmarkLookupParams(b *testing.B) {
+	routes := []routem.Route{
+		&testRoute{path: "/users/:id"},
+		&testRoute{path: "/teams/:team/members/:member"},
+		&testRoute{path: "/static/*filepath"},
+	}
+
+	handler, err := NewHandlerFactory(nil, nil).Handler(routes)
+	require.Nil(b, err)
+	root := handler.(*rootNode)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		parts, _ := root.splitPath("/teams/core/members/42")
+		info, _ := root.find(parts, routem.Get)
+		routeParams(info, parts)
+	}
+}
+
+func newFactory(ctx context.Context, errorHandler routem.ErrorHandlerFunc) routem.HandlerFactory {
+	return NewHandlerFactory(ctx, errorHandler)
+}
+
+func TestConformance(t *testing.T) {
+	routemtest.Run(t, newFactory)
+}
+
+func TestPrecedence(t *testing.T) {
+	routemtest.RunPrecedence(t, newFactory)
+}
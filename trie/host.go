@@ -0,0 +1,141 @@
+package trie
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/nick-codes/routem"
+)
+
+// hostNode holds the tree of routes for a single host pattern such
+// as "admin.example.com" or ":tenant.example.com".
+type hostNode struct {
+	pattern string
+	labels  []string
+	params  map[int]string
+	node
+}
+
+func newHostNode(pattern string) (*hostNode, error) {
+	pattern = strings.ToLower(pattern)
+	labels := strings.Split(pattern, ".")
+	params := make(map[int]string)
+	names := make(map[string]struct{}, len(labels))
+
+	for i, label := range labels {
+		if len(label) == 0 {
+			return nil, fmt.Errorf("Host contains an empty label: %s", pattern)
+		}
+		if strings.ContainsAny(label, ":/") && !strings.HasPrefix(label, ":") {
+			return nil, fmt.Errorf("Host contains an invalid label: %s", pattern)
+		}
+		if strings.HasPrefix(label, ":") {
+			name := label[1:]
+			if len(name) == 0 || strings.Contains(name, ":") {
+				return nil, fmt.Errorf("Found an invalid host parameter: %s", pattern)
+			}
+			if _, exists := names[name]; exists {
+				return nil, fmt.Errorf("Host has duplicate parameter: %s", label)
+			}
+			names[name] = struct{}{}
+			params[i] = name
+			labels[i] = ":"
+		}
+	}
+
+	tree, err := newNode("")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &hostNode{
+		pattern: pattern,
+		labels:  labels,
+		params:  params,
+		node:    *tree,
+	}, nil
+}
+
+// match reports whether the given host labels match this pattern.
+func (h *hostNode) match(labels []string) bool {
+	if len(labels) != len(h.labels) {
+		return false
+	}
+	for i, label := range h.labels {
+		if label != ":" && label != labels[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// hostParams adds the values of the host parameters to params.
+func (h *hostNode) hostParams(labels []string, params routem.Params) routem.Params {
+	if len(h.params) == 0 {
+		return params
+	}
+	if params == nil {
+		params = make(routem.Params, len(h.params))
+	}
+	for index, name := range h.params {
+		params[name] = labels[index]
+	}
+	return params
+}
+
+// sortHosts orders hosts so that static labels take precedence over
+// parameters, comparing from the left most label.
+func sortHosts(hosts []*hostNode) {
+	sort.SliceStable(hosts, func(i, j int) bool {
+		a, b := hosts[i].labels, hosts[j].labels
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if (a[k] == ":") != (b[k] == ":") {
+				return b[k] == ":"
+			}
+		}
+		return hosts[i].pattern < hosts[j].pattern
+	})
+}
+
+// hostLabels strips any port from a request host and splits it into
+// its lower cased labels.
+func hostLabels(host string) []string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.Split(strings.ToLower(host), ".")
+}
+
+// hasRoutes reports whether any route exists for the given path parts.
+func (n *node) hasRoutes(parts []string) bool {
+	if !n.matches(parts[0]) {
+		return false
+	}
+
+	if len(parts) == 1 || n.path == "*" {
+		return len(n.routes) > 0
+	}
+
+	subParts := parts[1:]
+	return n.each(subParts[0], func(child *node) bool {
+		return child.hasRoutes(subParts)
+	})
+}
+
+// tree selects the tree for a request. The first host pattern which
+// matches the request host and has routes for the path wins, falling
+// back to the routes without a host.
+func (root *rootNode) tree(host string, parts []string) (*node, *hostNode, []string) {
+	if len(root.hosts) > 0 {
+		labels := hostLabels(host)
+		for _, h := range root.hosts {
+			if h.match(labels) && h.hasRoutes(parts) {
+				return &h.node, h, labels
+			}
+		}
+	}
+	return &root.node, nil, nil
+}
@@ -0,0 +1,113 @@
+package trie
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/nick-codes/routem"
+	"github.com/nick-codes/routem/routemtest"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func hostRoute(name, host, path string) routem.Route {
+	route := namedRoute(name, path).(*testRoute)
+	route.host = host
+	return route
+}
+
+func assertHost(t *testing.T, handler http.Handler, host, path string, code int, body string) {
+	response := httptest.NewRecorder()
+	request, err := http.NewRequest("GET", "http://"+host+path, nil)
+	require.Nil(t, err)
+
+	handler.ServeHTTP(response, request)
+
+	assert.Equal(t, code, response.Code, host+path)
+	if code == 200 {
+		assert.Equal(t, body, response.Body.String(), host+path)
+	}
+}
+
+func TestErrorWithInvalidHosts(t *testing.T) {
+	for _, host := range []string{
+		"example..com",
+		":.example.com",
+		"example.com:8080",
+		"a:b.example.com",
+		":id.:id.example.com",
+	} {
+		assertError(t, []routem.Route{
+			&testRoute{host: host, path: "/test"},
+		})
+	}
+}
+
+func TestErrorWithHostAndPathParamCollision(t *testing.T) {
+	routes := []routem.Route{
+		&testRoute{host: ":id.example.com", path: "/:id"},
+	}
+	assertError(t, routes)
+}
+
+func TestErrorWithConflictingHosts(t *testing.T) {
+	routes := []routem.Route{
+		&testRoute{host: ":tenant.example.com", path: "/test"},
+		&testRoute{host: ":other.example.com", path: "/test"},
+	}
+	assertError(t, routes)
+}
+
+func TestSameRouteDifferentHosts(t *testing.T) {
+	routes := []routem.Route{
+		&testRoute{host: "admin.example.com", path: "/test"},
+		&testRoute{host: "www.example.com", path: "/test"},
+		&testRoute{path: "/test"},
+	}
+	assertSuccess(t, routes)
+}
+
+func TestHostRouting(t *testing.T) {
+	routes := []routem.Route{
+		hostRoute("admin", "admin.example.com", "/"),
+		hostRoute("tenant", ":tenant.example.com", "/"),
+		hostRoute("tenantUser", ":tenant.example.com", "/users/:id"),
+		hostRoute("region", ":tenant.:region.example.com", "/"),
+		hostRoute("default", "", "/"),
+		hostRoute("defaultOnly", "", "/about"),
+	}
+
+	for _, perm := range permutations(routes) {
+		handler, err := NewHandlerFactory(nil, nil).Handler(perm)
+		require.Nil(t, err)
+
+		assertHost(t, handler, "admin.example.com", "/", 200, "admin")
+		assertHost(t, handler, "ADMIN.example.com:8080", "/", 200, "admin")
+		assertHost(t, handler, "acme.example.com", "/", 200, "tenant tenant=acme")
+		assertHost(t, handler, "acme.example.com", "/users/5", 200, "tenantUser id=5 tenant=acme")
+		assertHost(t, handler, "acme.eu.example.com", "/", 200, "region region=eu tenant=acme")
+		assertHost(t, handler, "example.com", "/", 200, "default")
+		assertHost(t, handler, "other.org", "/", 200, "default")
+		assertHost(t, handler, "acme.example.com", "/about", 200, "defaultOnly")
+		assertHost(t, handler, "other.org", "/users/5", 404, "")
+	}
+}
+
+func TestMountRouterInHost(t *testing.T) {
+	admin := routem.NewRouter(nil)
+	admin.Get("/secret", routemtest.Echo("secret"))
+
+	router := routem.NewRouter(NewHandlerFactory(nil, nil))
+	router.WithHost("admin.example.com").MountRouter("/admin", admin)
+	router.Get("/", routemtest.Echo("public"))
+
+	handler, err := router.Handler()
+	require.Nil(t, err)
+
+	assertHost(t, handler, "admin.example.com", "/admin/secret", 200, "secret")
+	assertHost(t, handler, "public.example.com", "/admin/secret", 404, "")
+	assertHost(t, handler, "public.example.com", "/", 200, "public")
+}
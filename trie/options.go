@@ -0,0 +1,71 @@
+package trie
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+
+	"github.com/nick-codes/routem"
+
+	"golang.org/x/net/context"
+)
+
+// An Option configures optional behaviour of the trie HandlerFactory.
+type Option func(*factory)
+
+// WithAutoOptions answers OPTIONS requests for any path with routes
+// by responding with the Allow header for that path. An explicitly
+// registered Options route for the path still takes priority.
+func WithAutoOptions() Option {
+	return func(f *factory) {
+		f.autoOptions = true
+	}
+}
+
+// WithAutoHead serves HEAD requests for paths with a Get route by
+// running the Get route and discarding the response body. An
+// explicitly registered Head route for the path still takes priority.
+func WithAutoHead() Option {
+	return func(f *factory) {
+		f.autoHead = true
+	}
+}
+
+// WithPanicHandler registers a hook which is called with every panic
+// recovered from a route handler, for example to report it. The hook
+// is called before the error is passed on to the error handlers.
+func WithPanicHandler(handler func(routem.PanicError, context.Context)) Option {
+	return func(f *factory) {
+		f.panicHandler = handler
+	}
+}
+
+// Compile time type assertions
+var _ http.Flusher = headResponseWriter{}
+var _ http.Hijacker = headResponseWriter{}
+
+// headResponseWriter discards the body written by a Get handler
+// which is serving a HEAD request. Flush and Hijack are passed on to
+// the real response, so handlers can still use them.
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w headResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+// Flush sends the headers if the real response supports flushing.
+func (w headResponseWriter) Flush() {
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack hands over the connection if the real response supports it.
+func (w headResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap returns the real response for http.ResponseController.
+func (w headResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,188 @@
+package trie
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/nick-codes/routem"
+
+	"golang.org/x/net/context"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func bodyRoute(path string, method []routem.Method, body string) routem.Route {
+	return &testRoute{
+		path:   path,
+		method: method,
+		handler: func(ctx context.Context) routem.HTTPError {
+			response := routem.ResponseWriterFromContext(ctx)
+			response.Header().Set("X-Body", body)
+			fmt.Fprint(response, body)
+			return nil
+		},
+	}
+}
+
+func TestAutoOptions(t *testing.T) {
+	routes := []routem.Route{
+		&testRoute{path: "/test"},
+		&testRoute{path: "/test", method: routem.PutMethod},
+	}
+
+	factory := NewHandlerFactory(nil, nil, WithAutoOptions())
+	response := assertServerFactory(t, factory, routes, routem.Options, "http://localhost/test")
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, "GET, OPTIONS, PUT", response.Header().Get("Allow"))
+	assert.Equal(t, "", response.Body.String())
+}
+
+func TestAutoOptionsDisabled(t *testing.T) {
+	routes := []routem.Route{
+		&testRoute{path: "/test"},
+	}
+
+	response := assertServer(t, routes, routem.Options, "http://localhost/test")
+	assert.Equal(t, 405, response.Code)
+	assert.Equal(t, "GET", response.Header().Get("Allow"))
+}
+
+func TestAutoOptionsNotFound(t *testing.T) {
+	routes := []routem.Route{
+		&testRoute{path: "/test"},
+	}
+
+	factory := NewHandlerFactory(nil, nil, WithAutoOptions())
+	response := assertServerFactory(t, factory, routes, routem.Options, "http://localhost/other")
+	assert.Equal(t, 404, response.Code)
+}
+
+func TestAutoOptionsExplicitRouteWins(t *testing.T) {
+	routes := []routem.Route{
+		&testRoute{path: "/test"},
+		bodyRoute("/test", routem.OptionsMethod, "explicit"),
+	}
+
+	factory := NewHandlerFactory(nil, nil, WithAutoOptions())
+	response := assertServerFactory(t, factory, routes, routem.Options, "http://localhost/test")
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, "explicit", response.Body.String())
+}
+
+func TestAutoHead(t *testing.T) {
+	routes := []routem.Route{
+		bodyRoute("/test", routem.GetMethod, "body"),
+	}
+
+	factory := NewHandlerFactory(nil, nil, WithAutoHead())
+	response := assertServerFactory(t, factory, routes, routem.Head, "http://localhost/test")
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, "body", response.Header().Get("X-Body"))
+	assert.Equal(t, "", response.Body.String())
+}
+
+func TestAutoHeadDisabled(t *testing.T) {
+	routes := []routem.Route{
+		bodyRoute("/test", routem.GetMethod, "body"),
+	}
+
+	response := assertServer(t, routes, routem.Head, "http://localhost/test")
+	assert.Equal(t, 405, response.Code)
+}
+
+func TestAutoHeadExplicitRouteWins(t *testing.T) {
+	routes := []routem.Route{
+		bodyRoute("/test", routem.GetMethod, "get"),
+		bodyRoute("/test", routem.HeadMethod, "head"),
+	}
+
+	factory := NewHandlerFactory(nil, nil, WithAutoHead())
+	response := assertServerFactory(t, factory, routes, routem.Head, "http://localhost/test")
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, "head", response.Header().Get("X-Body"))
+}
+
+func TestAutoHeadAndOptionsAllow(t *testing.T) {
+	routes := []routem.Route{
+		&testRoute{path: "/test"},
+	}
+
+	factory := NewHandlerFactory(nil, nil, WithAutoHead(), WithAutoOptions())
+	response := assertServerFactory(t, factory, routes, routem.Put, "http://localhost/test")
+	assert.Equal(t, http.StatusMethodNotAllowed, response.Code)
+	assert.Equal(t, "GET, HEAD, OPTIONS", response.Header().Get("Allow"))
+}
+
+func TestWithPanicHandler(t *testing.T) {
+	var reported routem.PanicError
+	routes := []routem.Route{
+		&testRoute{
+			path: "/test",
+			handler: func(ctx context.Context) routem.HTTPError {
+				panic("boom")
+			},
+		},
+	}
+
+	factory := NewHandlerFactory(nil, nil, WithPanicHandler(func(err routem.PanicError, ctx context.Context) {
+		reported = err
+	}))
+	response := assertServerFactory(t, factory, routes, routem.Get, "http://localhost/test")
+	assert.Equal(t, 500, response.Code)
+	assert.NotNil(t, reported)
+	assert.Equal(t, "boom", reported.Value())
+}
+
+func TestAutoHeadFlush(t *testing.T) {
+	var flushErr error
+	routes := []routem.Route{
+		&testRoute{
+			path: "/test",
+			handler: func(ctx context.Context) routem.HTTPError {
+				controller := http.NewResponseController(routem.ResponseWriterFromContext(ctx))
+				flushErr = controller.Flush()
+				return nil
+			},
+		},
+	}
+
+	factory := NewHandlerFactory(nil, nil, WithAutoHead())
+	response := assertServerFactory(t, factory, routes, routem.Head, "http://localhost/test")
+	assert.Nil(t, flushErr)
+	assert.True(t, response.Flushed)
+	assert.Equal(t, 200, response.Code)
+}
+
+func TestAutoHeadHijack(t *testing.T) {
+	routes := []routem.Route{
+		&testRoute{
+			path: "/test",
+			handler: func(ctx context.Context) routem.HTTPError {
+				conn, rw, err := http.NewResponseController(routem.ResponseWriterFromContext(ctx)).Hijack()
+				if err != nil {
+					return routem.NewHTTPError(http.StatusInternalServerError, err)
+				}
+				defer conn.Close()
+
+				fmt.Fprint(rw, "HTTP/1.1 204 No Content\r\nConnection: close\r\n\r\n")
+				rw.Flush()
+				return nil
+			},
+		},
+	}
+
+	handler, err := NewHandlerFactory(nil, nil, WithAutoHead()).Handler(routes)
+	require.Nil(t, err)
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	response, err := http.Head(server.URL + "/test")
+	require.Nil(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusNoContent, response.StatusCode)
+}
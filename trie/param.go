@@ -0,0 +1,95 @@
+package trie
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Named constraints which may be used in place of a regular
+// expression, as in ":id<int>".
+var constraints = map[string]string{
+	"int":  `-?[0-9]+`,
+	"uint": `[0-9]+`,
+	"uuid": `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`,
+}
+
+// splitParam splits a parameter segment such as ":id<int>" into
+// the name and the constraint, if any.
+func splitParam(segment string) (string, string) {
+	name := segment[1:]
+	constraint := ""
+	if i := strings.Index(name, "<"); i >= 0 {
+		name, constraint = name[:i], name[i:]
+	}
+	return name, constraint
+}
+
+// parseConstraint compiles a constraint of the form "<int>" or
+// "<[a-z0-9-]+>" into a regular expression which must match the
+// whole segment.
+func parseConstraint(segment, constraint string) (*regexp.Regexp, error) {
+	if len(constraint) == 0 {
+		return nil, nil
+	}
+
+	if !strings.HasSuffix(constraint, ">") || len(constraint) < 3 {
+		return nil, fmt.Errorf("Found an invalid parameter constraint: %s", segment)
+	}
+
+	expr := constraint[1 : len(constraint)-1]
+	if named, exists := constraints[expr]; exists {
+		expr = named
+	}
+
+	pattern, err := regexp.Compile("^(?:" + expr + ")$")
+
+	if err != nil {
+		return nil, fmt.Errorf("Found an invalid parameter constraint: %s: %s", segment, err)
+	}
+
+	return pattern, nil
+}
+
+// matches reports whether the node accepts the given path segment.
+func (n *node) matches(segment string) bool {
+	switch n.path {
+	case "*":
+		return true
+	case ":":
+		if n.nonEmpty && len(segment) == 0 {
+			return false
+		}
+		return n.pattern == nil || n.pattern.MatchString(segment)
+	default:
+		return segment == n.path
+	}
+}
+
+// rejectEmpty stops the parameters in the tree from matching an
+// empty segment.
+func (n *node) rejectEmpty() {
+	if n.path == ":" {
+		n.nonEmpty = true
+	}
+	for _, child := range n.children {
+		child.rejectEmpty()
+	}
+	for _, child := range n.params {
+		child.rejectEmpty()
+	}
+}
+
+// each visits the children which accept the given segment in order
+// of precedence until visit returns true.
+func (n *node) each(segment string, visit func(*node) bool) bool {
+	if child := n.children[segment]; child != nil && visit(child) {
+		return true
+	}
+	for _, child := range n.params {
+		if child.matches(segment) && visit(child) {
+			return true
+		}
+	}
+	return n.catchAll != nil && visit(n.catchAll)
+}
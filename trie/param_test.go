@@ -0,0 +1,111 @@
+package trie
+
+import (
+	"github.com/nick-codes/routem"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorWithInvalidConstraints(t *testing.T) {
+	for _, path := range []string{
+		"/items/:id<>",
+		"/items/:id<int",
+		"/items/:id<[a-z>",
+		"/items/:<int>",
+		"/items/*rest<int>",
+	} {
+		assertError(t, []routem.Route{
+			&testRoute{path: path},
+		})
+	}
+}
+
+func TestErrorWithDuplicateConstrainedRoutes(t *testing.T) {
+	routes := []routem.Route{
+		&testRoute{path: "/items/:id<int>"},
+		&testRoute{path: "/items/:num<int>"},
+	}
+	assertError(t, routes)
+}
+
+func TestErrorWithConstrainedDuplicateParamName(t *testing.T) {
+	routes := []routem.Route{
+		&testRoute{path: "/:id<int>/:id"},
+	}
+	assertError(t, routes)
+}
+
+func TestConstrainedRouting(t *testing.T) {
+	routes := []routem.Route{
+		namedRoute("int", "/items/:id<int>"),
+		namedRoute("uuid", "/items/:uuid<uuid>"),
+		namedRoute("slug", "/items/:slug<[a-z0-9-]+>"),
+		namedRoute("any", "/items/:name"),
+		namedRoute("new", "/items/new"),
+		namedRoute("intPosts", "/users/:id<uint>/posts"),
+		namedRoute("rest", "/users/*rest"),
+	}
+
+	cases := []struct {
+		url  string
+		code int
+		body string
+	}{
+		{"/items/42", 200, "int id=42"},
+		{"/items/-42", 200, "int id=-42"},
+		{"/items/123e4567-e89b-12d3-a456-426614174000", 200, "uuid uuid=123e4567-e89b-12d3-a456-426614174000"},
+		{"/items/my-item-2", 200, "slug slug=my-item-2"},
+		{"/items/My_Item", 200, "any name=My_Item"},
+		{"/items/new", 200, "new"},
+		{"/users/42/posts", 200, "intPosts id=42"},
+		{"/users/abc/posts", 200, "rest rest=abc/posts"},
+	}
+
+	// Overlapping constraints are tried in the order they were given
+	// but static and unconstrained routes may come in any order
+	orders := [][]routem.Route{
+		routes,
+		{routes[3], routes[4], routes[0], routes[1], routes[2], routes[5], routes[6]},
+		{routes[6], routes[0], routes[3], routes[1], routes[4], routes[2], routes[5]},
+	}
+
+	for _, perm := range orders {
+		for _, c := range cases {
+			response := assertServer(t, perm, routem.Get, "http://localhost"+c.url)
+			assert.Equal(t, c.code, response.Code, c.url)
+			assert.Equal(t, c.body, response.Body.String(), c.url)
+		}
+	}
+}
+
+func TestConstrainedNotFound(t *testing.T) {
+	routes := []routem.Route{
+		&testRoute{path: "/items/:id<int>"},
+	}
+
+	response := assertServer(t, routes, routem.Get, "http://localhost/items/abc")
+	assert.Equal(t, 404, response.Code)
+
+	response = assertServer(t, routes, routem.Put, "http://localhost/items/abc")
+	assert.Equal(t, 404, response.Code)
+
+	response = assertServer(t, routes, routem.Put, "http://localhost/items/5")
+	assert.Equal(t, 405, response.Code)
+}
+
+func TestOverlappingConstraintsInOrder(t *testing.T) {
+	routes := []routem.Route{
+		namedRoute("slug", "/items/:slug<[a-z0-9-]+>"),
+		namedRoute("int", "/items/:id<int>"),
+	}
+
+	response := assertServer(t, routes, routem.Get, "http://localhost/items/42")
+	assert.Equal(t, "slug slug=42", response.Body.String())
+
+	routes[0], routes[1] = routes[1], routes[0]
+
+	response = assertServer(t, routes, routem.Get, "http://localhost/items/42")
+	assert.Equal(t, "int id=42", response.Body.String())
+}
@@ -0,0 +1,193 @@
+package trie
+
+import (
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+)
+
+// A PathPolicy decides how the trie HandlerFactory treats request
+// paths which do not exactly match a route but would after being
+// cleaned, that is with duplicate slashes and dot segments removed
+// or the trailing slash added or removed.
+//
+// Under StrictPaths a parameter matches an empty segment, so
+// "/users/" is served by "/users/:id". The other policies leave an
+// empty segment to be corrected, so "/users/" is treated as "/users".
+type PathPolicy int
+
+const (
+	// StrictPaths only serves exact matches. This is the default.
+	StrictPaths PathPolicy = iota
+	// RedirectPaths redirects the client to the canonical path.
+	RedirectPaths
+	// LenientPaths serves the canonical path without a redirect.
+	LenientPaths
+)
+
+// WithPathPolicy sets the PathPolicy for the factory.
+func WithPathPolicy(policy PathPolicy) Option {
+	return func(f *factory) {
+		f.pathPolicy = policy
+	}
+}
+
+// WithRedirectCode sets the status code used for path redirects,
+// such as http.StatusMovedPermanently or
+// http.StatusPermanentRedirect. By default GET and HEAD requests are
+// redirected with a 301 and all other methods with a 308 so the
+// method and body are preserved.
+func WithRedirectCode(code int) Option {
+	return func(f *factory) {
+		f.redirectCode = code
+	}
+}
+
+// WithCaseInsensitiveRedirect redirects requests whose path only
+// matches a route when the case of static segments is ignored to the
+// path of that route. This applies regardless of the PathPolicy.
+func WithCaseInsensitiveRedirect() Option {
+	return func(f *factory) {
+		f.foldCase = true
+	}
+}
+
+// cleanPath removes duplicate slashes and dot segments while keeping
+// any trailing slash.
+func cleanPath(p string) string {
+	cleaned := path.Clean(p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// toggleSlash adds or removes the trailing slash of a path.
+func toggleSlash(p string) string {
+	if strings.HasSuffix(p, "/") {
+		return strings.TrimSuffix(p, "/")
+	}
+	return p + "/"
+}
+
+// fixPath looks for a path with routes which the escaped request
+// path can be corrected to. It also reports if the correction must be a
+// redirect regardless of the PathPolicy.
+//
+// Only cleaned paths are considered, so a correction never begins
+// with an empty segment which a client would read as a host.
+func (root *rootNode) fixPath(host string, requestPath string) (string, bool) {
+	cleaned := cleanPath(requestPath)
+	candidates := []string{cleaned}
+
+	if root.pathPolicy != StrictPaths {
+		if cleaned != "/" {
+			candidates = append(candidates, toggleSlash(cleaned))
+		}
+
+		for _, candidate := range candidates {
+			if candidate == requestPath {
+				continue
+			}
+			parts, err := root.splitPath(candidate)
+			if err != nil {
+				continue
+			}
+			if tree, _, _ := root.tree(host, parts); tree.hasRoutes(parts) {
+				return candidate, false
+			}
+		}
+	}
+
+	if root.foldCase {
+		labels := hostLabels(host)
+		for _, candidate := range candidates {
+			parts, err := root.splitPath(candidate)
+			if err != nil {
+				continue
+			}
+			for _, h := range root.hosts {
+				if h.match(labels) {
+					if folded, ok := h.foldPath(parts, nil); ok {
+						return root.escapeParts(folded), true
+					}
+				}
+			}
+			if folded, ok := root.foldPath(parts, nil); ok {
+				return root.escapeParts(folded), true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// foldPath finds a route for the given parts ignoring the case of
+// static segments and returns the parts of the path for that route.
+func (n *node) foldPath(parts []string, folded []string) ([]string, bool) {
+	switch n.path {
+	case "*":
+		return append(folded, parts...), len(n.routes) > 0
+	case ":":
+		if !n.matches(parts[0]) {
+			return nil, false
+		}
+		folded = append(folded, parts[0])
+	default:
+		if !strings.EqualFold(n.path, parts[0]) {
+			return nil, false
+		}
+		folded = append(folded, n.path)
+	}
+
+	if len(parts) == 1 {
+		return folded, len(n.routes) > 0
+	}
+
+	// Visit static children in a stable order
+	keys := make([]string, 0, len(n.children))
+	for key := range n.children {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	children := make([]*node, 0, len(keys)+len(n.params)+1)
+	for _, key := range keys {
+		children = append(children, n.children[key])
+	}
+	children = append(children, n.params...)
+	if n.catchAll != nil {
+		children = append(children, n.catchAll)
+	}
+
+	for _, child := range children {
+		if result, ok := child.foldPath(parts[1:], folded); ok {
+			return result, true
+		}
+	}
+
+	return nil, false
+}
+
+// redirect sends the client to the given path, keeping the query.
+func (root *rootNode) redirect(response http.ResponseWriter, request *http.Request, p string) {
+	code := root.redirectCode
+	if code == 0 {
+		code = http.StatusPermanentRedirect
+		if request.Method == http.MethodGet || request.Method == http.MethodHead {
+			code = http.StatusMovedPermanently
+		}
+	}
+
+	// A Location beginning "//" or "/\" would name another host
+	if len(p) > 1 && (p[1] == '/' || p[1] == '\\') {
+		p = "/" + strings.TrimLeft(p, "/\\")
+	}
+
+	if len(request.URL.RawQuery) > 0 {
+		p += "?" + request.URL.RawQuery
+	}
+
+	http.Redirect(response, request, p, code)
+}
@@ -0,0 +1,154 @@
+package trie
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/nick-codes/routem"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pathRoutes() []routem.Route {
+	return []routem.Route{
+		namedRoute("users", "/users"),
+		namedRoute("user", "/users/:id"),
+		namedRoute("docs", "/docs/"),
+		namedRoute("about", "/About/Team"),
+		namedRoute("root", "/"),
+	}
+}
+
+func TestStrictPaths(t *testing.T) {
+	for _, url := range []string{"/docs", "//users", "/a/../users", "/users/./5", "/about/team"} {
+		response := assertServer(t, pathRoutes(), routem.Get, "http://localhost"+url)
+		assert.Equal(t, http.StatusNotFound, response.Code, url)
+	}
+}
+
+func TestStrictPathsParamMatchesEmptySegment(t *testing.T) {
+	response := assertServer(t, pathRoutes(), routem.Get, "http://localhost/users/")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "user id=", response.Body.String())
+}
+
+func TestParamDoesNotMatchEmptySegment(t *testing.T) {
+	routes := append(pathRoutes(), namedRoute("posts", "/users/:id/posts"))
+
+	factory := NewHandlerFactory(nil, nil, WithPathPolicy(LenientPaths))
+	response := assertServerFactory(t, factory, routes, routem.Get, "http://localhost/users//posts")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "user id=posts", response.Body.String())
+}
+
+func TestRedirectPaths(t *testing.T) {
+	cases := []struct {
+		method   routem.Method
+		url      string
+		code     int
+		location string
+	}{
+		{routem.Get, "/users/", 301, "/users"},
+		{routem.Get, "/docs", 301, "/docs/"},
+		{routem.Get, "//users", 301, "/users"},
+		{routem.Get, "/a/../users/5", 301, "/users/5"},
+		{routem.Get, "/users/./5/?q=1", 301, "/users/5?q=1"},
+		{routem.Head, "/users//5", 301, "/users/5"},
+		{routem.Put, "/users/", 308, "/users"},
+	}
+
+	factory := NewHandlerFactory(nil, nil, WithPathPolicy(RedirectPaths))
+	for _, c := range cases {
+		response := assertServerFactory(t, factory, pathRoutes(), c.method, "http://localhost"+c.url)
+		assert.Equal(t, c.code, response.Code, c.url)
+		assert.Equal(t, c.location, response.Header().Get("Location"), c.url)
+	}
+}
+
+func TestRedirectPathsNotFound(t *testing.T) {
+	factory := NewHandlerFactory(nil, nil, WithPathPolicy(RedirectPaths))
+	response := assertServerFactory(t, factory, pathRoutes(), routem.Get, "http://localhost/missing/")
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
+
+func TestRedirectCode(t *testing.T) {
+	factory := NewHandlerFactory(nil, nil, WithPathPolicy(RedirectPaths), WithRedirectCode(http.StatusFound))
+	response := assertServerFactory(t, factory, pathRoutes(), routem.Put, "http://localhost/users/")
+	assert.Equal(t, http.StatusFound, response.Code)
+	assert.Equal(t, "/users", response.Header().Get("Location"))
+}
+
+func TestLenientPaths(t *testing.T) {
+	cases := []struct {
+		url  string
+		body string
+	}{
+		{"/users/", "users"},
+		{"/docs", "docs"},
+		{"//users///5", "user id=5"},
+		{"/x/../users/./5/", "user id=5"},
+	}
+
+	factory := NewHandlerFactory(nil, nil, WithPathPolicy(LenientPaths))
+	for _, c := range cases {
+		response := assertServerFactory(t, factory, pathRoutes(), routem.Get, "http://localhost"+c.url)
+		assert.Equal(t, http.StatusOK, response.Code, c.url)
+		assert.Equal(t, c.body, response.Body.String(), c.url)
+	}
+}
+
+func TestCaseInsensitiveRedirect(t *testing.T) {
+	factory := NewHandlerFactory(nil, nil, WithCaseInsensitiveRedirect())
+
+	response := assertServerFactory(t, factory, pathRoutes(), routem.Get, "http://localhost/about/TEAM")
+	assert.Equal(t, http.StatusMovedPermanently, response.Code)
+	assert.Equal(t, "/About/Team", response.Header().Get("Location"))
+
+	response = assertServerFactory(t, factory, pathRoutes(), routem.Get, "http://localhost/USERS/Bob")
+	assert.Equal(t, http.StatusMovedPermanently, response.Code)
+	assert.Equal(t, "/users/Bob", response.Header().Get("Location"))
+
+	// Cleaning only happens with a PathPolicy
+	response = assertServerFactory(t, factory, pathRoutes(), routem.Get, "http://localhost/about/team/")
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
+
+func TestCaseInsensitiveRedirectWithLenientPaths(t *testing.T) {
+	factory := NewHandlerFactory(nil, nil, WithPathPolicy(LenientPaths), WithCaseInsensitiveRedirect())
+
+	response := assertServerFactory(t, factory, pathRoutes(), routem.Get, "http://localhost//about/team/")
+	assert.Equal(t, http.StatusMovedPermanently, response.Code)
+	assert.Equal(t, "/About/Team", response.Header().Get("Location"))
+}
+
+func TestCaseInsensitiveRedirectStaysOnHost(t *testing.T) {
+	routes := []routem.Route{
+		namedRoute("info", "/:tenant/:page/Info"),
+	}
+
+	factory := NewHandlerFactory(nil, nil, WithCaseInsensitiveRedirect())
+	for _, url := range []string{"//evil.example/info", "///evil.example/x/info", "/./evil.example/info"} {
+		response := assertServerFactory(t, factory, routes, routem.Get, "http://localhost"+url)
+		assert.False(t, strings.HasPrefix(response.Header().Get("Location"), "//"), url)
+	}
+
+	response := assertServerFactory(t, factory, routes, routem.Get, "http://localhost//acme/home/info")
+	assert.Equal(t, http.StatusMovedPermanently, response.Code)
+	assert.Equal(t, "/acme/home/Info", response.Header().Get("Location"))
+}
+
+func TestRedirectNeverLeavesHost(t *testing.T) {
+	root := &rootNode{}
+	for _, p := range []string{"//evil.example/", "/\\evil.example/", "///evil.example"} {
+		response := httptest.NewRecorder()
+		request, err := http.NewRequest("GET", "http://localhost/", nil)
+		require.Nil(t, err)
+
+		root.redirect(response, request, p)
+		assert.Equal(t, "/evil.example", strings.TrimSuffix(response.Header().Get("Location"), "/"), p)
+	}
+}
@@ -0,0 +1,125 @@
+package trie
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/nick-codes/routem"
+
+	"golang.org/x/net/context"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testTimeout = 50 * time.Millisecond
+
+func TestTimeoutLateWriteFails(t *testing.T) {
+	done := make(chan error)
+	routes := []routem.Route{
+		&testRoute{
+			path:    "/test",
+			timeout: testTimeout,
+			handler: func(ctx context.Context) routem.HTTPError {
+				<-ctx.Done()
+				_, err := fmt.Fprint(routem.ResponseWriterFromContext(ctx), "late")
+				done <- err
+				return nil
+			},
+			errorHandler: func(err routem.HTTPError, ctx context.Context) error {
+				http.Error(routem.ResponseWriterFromContext(ctx), "Timed Out", err.Code())
+				return nil
+			},
+		},
+	}
+
+	response := assertServer(t, routes, routem.Get, "http://localhost/test")
+	assert.Equal(t, http.StatusRequestTimeout, response.Code)
+	assert.Equal(t, routem.ErrHandlerTimeout, <-done)
+	assert.Equal(t, "Timed Out\n", response.Body.String())
+}
+
+func TestTimeoutLateHeadersIgnored(t *testing.T) {
+	done := make(chan struct{})
+	routes := []routem.Route{
+		&testRoute{
+			path:    "/test",
+			timeout: testTimeout,
+			handler: func(ctx context.Context) routem.HTTPError {
+				<-ctx.Done()
+				response := routem.ResponseWriterFromContext(ctx)
+				response.Header().Set("X-Late", "true")
+				response.WriteHeader(http.StatusTeapot)
+				close(done)
+				return nil
+			},
+		},
+	}
+
+	response := assertServer(t, routes, routem.Get, "http://localhost/test")
+	<-done
+	assert.Equal(t, http.StatusInternalServerError, response.Code)
+	assert.Equal(t, "", response.Header().Get("X-Late"))
+}
+
+func TestTimeoutAfterCommit(t *testing.T) {
+	called := false
+	committed := make(chan bool, 1)
+	routes := []routem.Route{
+		&testRoute{
+			path:    "/test",
+			timeout: testTimeout,
+			handler: func(ctx context.Context) routem.HTTPError {
+				response := routem.ResponseWriterFromContext(ctx)
+				fmt.Fprint(response, "partial")
+				committed <- response.(routem.CommitReporter).Committed()
+				<-ctx.Done()
+				return nil
+			},
+			errorHandler: func(err routem.HTTPError, ctx context.Context) error {
+				called = true
+				return nil
+			},
+		},
+	}
+
+	response := assertServer(t, routes, routem.Get, "http://localhost/test")
+	assert.True(t, <-committed)
+	assert.False(t, called)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "partial", response.Body.String())
+}
+
+func TestHeadersKeptWithoutWrite(t *testing.T) {
+	routes := []routem.Route{
+		&testRoute{
+			path: "/test",
+			handler: func(ctx context.Context) routem.HTTPError {
+				routem.ResponseWriterFromContext(ctx).Header().Set("X-Test", "kept")
+				return nil
+			},
+		},
+	}
+
+	response := assertServer(t, routes, routem.Get, "http://localhost/test")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "kept", response.Header().Get("X-Test"))
+}
+
+func TestHeadersKeptWithError(t *testing.T) {
+	routes := []routem.Route{
+		&testRoute{
+			path: "/test",
+			handler: func(ctx context.Context) routem.HTTPError {
+				routem.ResponseWriterFromContext(ctx).Header().Set("X-Test", "kept")
+				return routem.NewHTTPError(http.StatusBadRequest, fmt.Errorf("Bad"))
+			},
+		},
+	}
+
+	response := assertServer(t, routes, routem.Get, "http://localhost/test")
+	assert.Equal(t, http.StatusInternalServerError, response.Code)
+	assert.Equal(t, "kept", response.Header().Get("X-Test"))
+}
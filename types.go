@@ -8,3 +8,4 @@ var _ Router = &router{}
 var _ Route = &route{}
 var _ Group = &group{}
 var _ Service = &service{}
+var _ PanicError = &panicError{}